@@ -0,0 +1,102 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Agent names recorded in invocations.agent. Humans are recorded as
+// agentHuman; rows from before detection existed have "".
+const (
+	agentHuman   = "human"
+	agentUnknown = "agent" // ACKCHYUALLY_AGENT=1, or a marker without a known name
+	agentClaude  = "claude-code"
+	agentCodex   = "codex"
+	agentCopilot = "copilot"
+)
+
+// agentEnvMarkers are set by coding agents in the environment of the
+// commands they run.
+var agentEnvMarkers = []struct{ key, agent string }{
+	{"CLAUDECODE", agentClaude},
+	{"CLAUDE_CODE_ENTRYPOINT", agentClaude},
+	{"CODEX_SANDBOX", agentCodex},
+	{"CODEX_SANDBOX_NETWORK_DISABLED", agentCodex},
+}
+
+// agentProcessNames maps executable names of agent CLIs to agent names. They
+// are matched against the shim's ancestor processes, which catches agents
+// that set no marker (or whose shell tool strips the environment).
+var agentProcessNames = map[string]string{
+	"claude":  agentClaude,
+	"codex":   agentCodex,
+	"copilot": agentCopilot,
+}
+
+// maxAncestors bounds the parent-process walk (agent -> shell -> make -> ...).
+const maxAncestors = 8
+
+// detectAgent names the coding agent that most likely invoked the shim, or
+// returns "" for a person. ACKCHYUALLY_AGENT overrides detection: 1/0 force
+// agent/human mode, any other valid name names the agent (the Copilot wrapper
+// installed by `ackchyually integrate copilot` sets it to "copilot"). Names
+// that aren't [a-z0-9._-]{1,32} are ignored, so stats aren't grouped by them.
+func detectAgent() string {
+	switch v := strings.TrimSpace(strings.ToLower(os.Getenv("ACKCHYUALLY_AGENT"))); v {
+	case "":
+	case "1", "true", "yes":
+		return agentUnknown
+	case "0", "false", "no", agentHuman:
+		return ""
+	default:
+		if isAgentName(v) {
+			return v
+		}
+	}
+	for _, m := range agentEnvMarkers {
+		if strings.TrimSpace(os.Getenv(m.key)) != "" {
+			return m.agent
+		}
+	}
+	return agentFromAncestors(os.Getppid())
+}
+
+// isAgentName reports whether s is a name to record as an agent.
+func isAgentName(s string) bool {
+	if s == "" || len(s) > 32 {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '.' && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// agentFromAncestors walks up from pid looking for a known agent CLI.
+func agentFromAncestors(pid int) string {
+	for i := 0; i < maxAncestors && pid > 1; i++ {
+		ppid, names, ok := processInfo(pid)
+		if !ok {
+			return ""
+		}
+		for _, n := range names {
+			n = strings.ToLower(strings.TrimSuffix(filepath.Base(n), ".exe"))
+			if a, ok := agentProcessNames[n]; ok {
+				return a
+			}
+		}
+		pid = ppid
+	}
+	return ""
+}
+
+// agentLabel is what gets recorded for an invocation.
+func agentLabel(agent string) string {
+	if agent == "" {
+		return agentHuman
+	}
+	return agent
+}
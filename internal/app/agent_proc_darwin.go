@@ -0,0 +1,17 @@
+//go:build darwin
+
+package app
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// processInfo asks the kernel for pid's parent and command name.
+func processInfo(pid int) (ppid int, names []string, ok bool) {
+	k, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil {
+		return 0, nil, false
+	}
+	comm := unix.ByteSliceToString(k.Proc.P_comm[:])
+	return int(k.Eproc.Ppid), []string{comm}, true
+}
@@ -0,0 +1,42 @@
+//go:build linux
+
+package app
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// processInfo reads pid's parent and names from /proc: the kernel's comm plus
+// the first two argv entries, so `node /path/to/copilot` is recognized too.
+func processInfo(pid int) (ppid int, names []string, ok bool) {
+	dir := "/proc/" + strconv.Itoa(pid)
+	stat, err := os.ReadFile(dir + "/stat")
+	if err != nil {
+		return 0, nil, false
+	}
+	// "pid (comm) state ppid ..."; comm may itself contain spaces or parens.
+	s := string(stat)
+	open, closing := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || closing < open {
+		return 0, nil, false
+	}
+	fields := strings.Fields(s[closing+1:])
+	if len(fields) < 2 {
+		return 0, nil, false
+	}
+	ppid, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, nil, false
+	}
+	names = append(names, s[open+1:closing])
+	if cmdline, err := os.ReadFile(dir + "/cmdline"); err == nil {
+		args := bytes.Split(bytes.TrimRight(cmdline, "\x00"), []byte{0})
+		for i := 0; i < len(args) && i < 2; i++ {
+			names = append(names, string(args[i]))
+		}
+	}
+	return ppid, names, true
+}
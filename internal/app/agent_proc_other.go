@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package app
+
+// processInfo is not implemented here; detection falls back to env markers.
+func processInfo(int) (ppid int, names []string, ok bool) {
+	return 0, nil, false
+}
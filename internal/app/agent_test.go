@@ -0,0 +1,103 @@
+package app
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func clearAgentEnv(t *testing.T) {
+	t.Helper()
+	for _, m := range agentEnvMarkers {
+		t.Setenv(m.key, "")
+	}
+	t.Setenv("ACKCHYUALLY_AGENT", "")
+}
+
+func TestDetectAgent(t *testing.T) {
+	clearAgentEnv(t)
+	if got := agentFromAncestors(os.Getppid()); got != "" {
+		t.Skipf("test is running under %s", got)
+	}
+	if got := detectAgent(); got != "" {
+		t.Fatalf("detectAgent with no markers = %q", got)
+	}
+	t.Setenv("CLAUDECODE", "1")
+	if got := detectAgent(); got != agentClaude {
+		t.Fatalf("detectAgent with CLAUDECODE=1 = %q", got)
+	}
+	t.Setenv("ACKCHYUALLY_AGENT", "0")
+	if got := detectAgent(); got != "" {
+		t.Fatalf("ACKCHYUALLY_AGENT=0 should override detection, got %q", got)
+	}
+	t.Setenv("ACKCHYUALLY_AGENT", "1")
+	if got := detectAgent(); got != agentUnknown {
+		t.Fatalf("detectAgent with ACKCHYUALLY_AGENT=1 = %q", got)
+	}
+	t.Setenv("ACKCHYUALLY_AGENT", "Copilot")
+	if got := detectAgent(); got != agentCopilot {
+		t.Fatalf("detectAgent with ACKCHYUALLY_AGENT=Copilot = %q", got)
+	}
+	t.Setenv("ACKCHYUALLY_AGENT", " My-Agent_2.0 ")
+	if got := detectAgent(); got != "my-agent_2.0" {
+		t.Fatalf("detectAgent with ACKCHYUALLY_AGENT=\" My-Agent_2.0 \" = %q", got)
+	}
+	// Invalid names fall back to detection (CLAUDECODE is still set).
+	for _, v := range []string{"rm -rf /", "agent\nname", strings.Repeat("a", 33)} {
+		t.Setenv("ACKCHYUALLY_AGENT", v)
+		if got := detectAgent(); got != agentClaude {
+			t.Fatalf("detectAgent with ACKCHYUALLY_AGENT=%q = %q, want detection", v, got)
+		}
+	}
+	if got := agentLabel(""); got != agentHuman {
+		t.Fatalf("agentLabel(\"\") = %q", got)
+	}
+}
+
+func TestProcessInfo(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("process lookup not implemented on " + runtime.GOOS)
+	}
+	ppid, names, ok := processInfo(os.Getpid())
+	if !ok {
+		t.Fatal("processInfo(self) failed")
+	}
+	if ppid != os.Getppid() {
+		t.Fatalf("ppid = %d, want %d", ppid, os.Getppid())
+	}
+	if len(names) == 0 || !strings.HasPrefix(names[0], "app.test") {
+		t.Fatalf("names = %q, want the test binary first", names)
+	}
+}
+
+func TestSuggestKnownGood_AgentOneLine(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	seedInvocation(t, ctxKey, "git", []string{"git", "log", "-1", "--pretty=%s"}, time.Now().Add(-time.Hour), 0)
+
+	_, _, errOut := captureStdoutStderr(t, func() int {
+		suggestKnownGood("git", ctxKey, []string{"git", "log", "--prety=%s"}, usageHints{Agent: agentCodex})
+		return 0
+	})
+	want := "ackchyually: suggestion: git log --pretty=%s  # keeps the rest of what you typed; fixes --prety→--pretty\n"
+	if !strings.HasPrefix(errOut, want) {
+		t.Fatalf("stderr = %q, want it to start with %q", errOut, want)
+	}
+	for _, l := range strings.Split(strings.TrimSpace(errOut), "\n") {
+		if !strings.HasPrefix(l, "ackchyually: ") {
+			t.Fatalf("agent output has a continuation line: %q", l)
+		}
+	}
+}
+
+func TestSuggestNoKnownGood_Agent(t *testing.T) {
+	setTempHomeAndCWD(t)
+	_, _, errOut := captureStdoutStderr(t, func() int {
+		suggestNoKnownGood("git", []string{"git", "stash", "--bogus"}, usageHints{Agent: agentUnknown})
+		return 0
+	})
+	if !strings.Contains(errOut, "run `git stash --help`") {
+		t.Fatalf("stderr = %q, want a pointer to the subcommand's help", errOut)
+	}
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/execx"
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+func TestClassifyInvocation(t *testing.T) {
+	setTempHomeAndCWD(t)
+	tests := []struct {
+		name string
+		tool string
+		args []string
+		res  execx.Result
+		want string
+	}{
+		{"ok", "git", []string{"status"}, execx.Result{Mode: "pipes"}, profile.ClassOK},
+		{"usage wins", "git", []string{"log", "--prety"}, execx.Result{ExitCode: 129, Mode: "pipes", StderrTail: "error: unknown option `prety'\nusage: git log"}, profile.ClassUsage},
+		{"usage on exit 0", "tool", []string{"x"}, execx.Result{Mode: "pipes", StderrTail: "unknown flag: --x\n"}, profile.ClassUsage},
+		{"usage kept in the head", "tool", []string{"x"}, execx.Result{ExitCode: 1, Mode: "pipes", StderrTail: "unknown flag: --x\n\n[... output omitted ...]\n  --verbose  print more\n", Truncated: true}, profile.ClassUsage},
+		{"auth", "git", []string{"push"}, execx.Result{ExitCode: 128, Mode: "pipes", StderrTail: "fatal: Authentication failed"}, profile.ClassAuth},
+		{"interrupted", "make", []string{"build"}, execx.Result{ExitCode: 130, Mode: "pipes"}, profile.ClassInterrupted},
+		{"full-screen session", "less", []string{"notes.txt"}, execx.Result{ExitCode: 1, Mode: "pty:in,out,err", CombinedTail: "Error: unknown key\n", FullScreen: true}, profile.ClassTUI},
+		{"signalled after clean exit", "tool", []string{"x"}, execx.Result{ExitCode: 143, Mode: "pipes", StderrTail: "unknown flag: --x\n", Signal: "SIGTERM", Interrupted: true}, profile.ClassInterrupted},
+		{"plain failure", "make", []string{"build"}, execx.Result{ExitCode: 2, Mode: "pipes", StderrTail: "make: *** [all] Error 1"}, profile.ClassError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyInvocation(profile.Load(tt.tool), tt.args, tt.res)
+			if got.Class != tt.want {
+				t.Fatalf("classifyInvocation = %+v, want class %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickLogin_PrefersLoginOverAuth(t *testing.T) {
+	now := time.Now()
+	cands := []store.SuccessCandidate{
+		{Argv: []string{"gh", "auth", "status"}, Count: 3, Last: now},
+		{Argv: []string{"gh", "auth", "login", "--with-token"}, Count: 1, Last: now.Add(-time.Hour)},
+		{Argv: []string{"gh", "pr", "list"}, Count: 9, Last: now},
+	}
+	got := pickLogin(cands, []string{"login", "auth"})
+	if strings.Join(got, " ") != "gh auth login --with-token" {
+		t.Fatalf("pickLogin = %v", got)
+	}
+	if got := pickLogin(cands[2:], []string{"login", "auth"}); got != nil {
+		t.Fatalf("pickLogin(no login) = %v, want nil", got)
+	}
+}
+
+func TestRunShim_AuthFailureSuggestsLastLogin(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	tmp := t.TempDir()
+	writeExec(t, tmp, "script", "#!/bin/sh\necho 'error: authentication required' >&2\nexit 1", "@echo error: authentication required 1>&2\r\n@exit /b 1")
+	t.Setenv("PATH", tmp+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	seedInvocation(t, ctxKey, "script", []string{"script", "login"}, time.Now(), 0)
+	seedInvocation(t, ctxKey, "script", []string{"script", "status"}, time.Now(), 0)
+
+	code, _, errOut := captureStdoutStderr(t, func() int {
+		return RunShim("script", []string{"deploy"})
+	})
+	if code != 1 {
+		t.Fatalf("RunShim returned %d want 1", code)
+	}
+	if !strings.Contains(errOut, "last successful login") || !strings.Contains(errOut, "script login") {
+		t.Fatalf("stderr missing login hint:\n%s", errOut)
+	}
+	if strings.Contains(errOut, "suggestion (previous success") {
+		t.Fatalf("auth failure should not print an argv suggestion:\n%s", errOut)
+	}
+
+	var invs []store.Invocation
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		invs, err = db.ListInvocations(ctxKey, "script", 1)
+		return err
+	}); err != nil {
+		t.Fatalf("ListInvocations: %v", err)
+	}
+	if len(invs) != 1 || invs[0].Class != profile.ClassAuth {
+		t.Fatalf("recorded invocation = %+v, want class auth", invs)
+	}
+}
+
+func TestStats_PrintsClassColumns(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	for _, inv := range []store.Invocation{
+		{At: time.Now(), ContextKey: ctxKey, Tool: "git", ArgvJSON: `["git","status"]`, Mode: "pipes", Class: profile.ClassOK, Agent: agentHuman},
+		{At: time.Now(), ContextKey: ctxKey, Tool: "git", ArgvJSON: `["git","log","--prety"]`, ExitCode: 129, Mode: "pipes", Class: profile.ClassUsage, Agent: agentCodex},
+		{At: time.Now(), ContextKey: ctxKey, Tool: "gh", ArgvJSON: `["gh","pr","list"]`, ExitCode: 4, Mode: "pipes", Class: profile.ClassAuth, Agent: agentCodex},
+	} {
+		if err := store.WithDB(func(db *store.DB) error { return db.InsertInvocation(inv) }); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	code, out, _ := captureStdoutStderr(t, func() int { return statsCmd(nil) })
+	if code != 0 {
+		t.Fatalf("stats returned %d", code)
+	}
+	for _, want := range []string{"tool", "ok", "usage", "auth", "git", "gh", "agent", "human", "codex"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("stats output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "network") {
+		t.Fatalf("stats output should only show used classes:\n%s", out)
+	}
+}
@@ -9,6 +9,13 @@ import (
 )
 
 func RunCLI(args []string) int {
+	if len(args) == 1 && args[0] == identifyCmdName {
+		return identifyQueuedCmd()
+	}
+	if len(args) > 0 && args[0] == "run" {
+		// The wrapped command is recorded as itself.
+		return runCmd(args[1:])
+	}
 	start := time.Now()
 	code := runCLI(args)
 	logCLIInvocation(start, time.Since(start), args, code)
@@ -29,13 +36,19 @@ func runCLI(args []string) int {
 		return tagCmd(args[1:])
 	case "export":
 		return exportCmd(args[1:])
+	case "history":
+		return historyCmd(args[1:])
+	case "stats":
+		return statsCmd(args[1:])
+	case "why":
+		return whyCmd(args[1:])
 	case "integrate":
 		return integrateCmd(args[1:])
 	case "version":
 		printVersion()
 		return 0
 	default:
-		printUnknownCommand(args[0], []string{"shim", "best", "tag", "export", "integrate", "version"})
+		printUnknownCommand(args[0], []string{"shim", "best", "tag", "export", "history", "stats", "why", "run", "integrate", "version"})
 		return 2
 	}
 }
@@ -53,6 +66,10 @@ Commands:
   tag add "<tag>" -- <command...>
   tag run "<tag>"
   export --format md|json [--tool <tool>]
+  history [--tool <tool>] [--limit <n>] [--tree]
+  stats [--all] [--perf [--tool <tool>] [--limit <n>]] [--versions [--tool <tool> [--min <version>]]]
+  why [--last|<invocation-id>]
+  run [--as <name>] -- <command...>
   integrate status
   integrate codex|claude|copilot|all [--dry-run] [--undo]
   integrate verify [codex|claude|copilot|all]
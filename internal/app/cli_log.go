@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/joelklabo/ackchyually/internal/contextkey"
+	"github.com/joelklabo/ackchyually/internal/profile"
 	"github.com/joelklabo/ackchyually/internal/redact"
 	"github.com/joelklabo/ackchyually/internal/store"
 )
@@ -16,6 +17,9 @@ func logCLIInvocation(start time.Time, dur time.Duration, args []string, exitCod
 	}
 
 	ctxKey := contextkey.Detect()
+	if _, optedOut := detectOptOut(ctxKey); optedOut {
+		return
+	}
 
 	r := redact.Default()
 	argvSafe := r.RedactArgs(append([]string{"ackchyually"}, args...))
@@ -30,8 +34,20 @@ func logCLIInvocation(start time.Time, dur time.Duration, args []string, exitCod
 			ArgvJSON:   store.MustJSON(argvSafe),
 			ExitCode:   exitCode,
 			Mode:       "cli",
+			Class:      cliClass(exitCode),
 		})
 	}); err != nil {
 		_ = err // best-effort
 	}
 }
+
+func cliClass(exitCode int) string {
+	switch exitCode {
+	case 0:
+		return profile.ClassOK
+	case 2:
+		return profile.ClassUsage
+	default:
+		return profile.ClassError
+	}
+}
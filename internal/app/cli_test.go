@@ -54,6 +54,12 @@ func captureStdoutStderr(t *testing.T, fn func() int) (code int, stdout string,
 	return code, string(outB), string(errB)
 }
 
+func init() {
+	// The test binary can't stand in for ackchyually: identify new tools
+	// and probe their vocabulary in-process, before the shim returns.
+	identifyInBackground = func() { _ = identifyQueuedCmd() }
+}
+
 func setTempHomeAndCWD(t *testing.T) string {
 	t.Helper()
 
@@ -65,6 +71,11 @@ func setTempHomeAndCWD(t *testing.T) string {
 
 	t.Setenv("HOME", home)
 	t.Setenv("USERPROFILE", home)
+	// Tests assert human-facing output even when the suite runs under an agent.
+	t.Setenv("ACKCHYUALLY_AGENT", "0")
+	// ...and as top-level calls even when `go test` itself ran under a shim.
+	t.Setenv(parentIDEnv, "")
+	t.Setenv("ACKCHYUALLY_LINEAGE", "")
 
 	oldCwd, err := os.Getwd()
 	if err != nil {
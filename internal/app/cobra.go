@@ -0,0 +1,115 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/joelklabo/ackchyually/internal/completion"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+// maxCommandDepth bounds how many nested subcommands are walked
+// (kubectl config view, gh pr list).
+const maxCommandDepth = 3
+
+// cobraVocabulary walks failed's subcommand path with `__complete`, stopping
+// at the first positional argument that is not a valid subcommand: that one
+// is checked against the subcommands valid at its depth, and flags are the
+// ones the deepest valid command accepts. ok is false for non-cobra tools.
+func cobraVocabulary(c *vocabCache, failed []string) (tokenVocab, bool) {
+	if !c.isCobra() {
+		return tokenVocab{}, false
+	}
+	v := tokenVocab{Flags: map[string]int{}, Subs: map[string]int{}, SubAt: -1}
+
+	var path []string
+	for i := 1; i < len(failed) && len(path) < maxCommandDepth; i++ {
+		a := failed[i]
+		if a == "" || strings.HasPrefix(a, "-") {
+			continue
+		}
+		subs := map[string]int{}
+		for _, w := range c.complete(append(append([]string(nil), path...), "")) {
+			if isCommandWord(w) {
+				subs[w] = 1
+			}
+		}
+		if len(subs) == 0 {
+			break
+		}
+		if subs[a] > 0 {
+			path = append(path, a)
+			continue
+		}
+		v.Subs, v.SubAt = subs, i
+		if fix, ok := closestToken(a, subs); ok {
+			path = append(path, fix)
+		}
+		break
+	}
+
+	for _, w := range c.complete(append(append([]string(nil), path...), "-")) {
+		if strings.HasPrefix(w, "-") {
+			name, _ := splitFlag(w)
+			v.Flags[name] = 1
+		}
+	}
+	return v, true
+}
+
+// isCobra checks the binary for cobra's completion protocol once per
+// identified binary. A binary not identified yet (see toolid.IdentifyQueued)
+// has no row to keep the answer on, so it gets no completions until it is.
+func (c *vocabCache) isCobra() bool {
+	if _, err := c.db.GetToolBySHA(c.sha); err != nil {
+		return false
+	}
+	kind, err := c.db.ToolCompletion(c.sha)
+	if err != nil {
+		return false
+	}
+	if kind == "" {
+		if !c.probe {
+			c.missed = true
+			return false
+		}
+		kind = store.CompletionNone
+		if completion.IsCobra(c.exe) {
+			kind = store.CompletionCobra
+		}
+		if err := c.db.SetToolCompletion(c.sha, kind); err != nil {
+			_ = err // best-effort
+		}
+	}
+	return kind == store.CompletionCobra
+}
+
+// complete is completion.Query cached per tool binary and argv. Failed
+// queries are cached as empty so a slow tool is only waited on once.
+func (c *vocabCache) complete(args []string) []string {
+	if words, ok, err := c.db.GetCompletion(c.sha, args); err == nil && ok {
+		return words
+	}
+	if !c.probe {
+		c.missed = true
+		return nil
+	}
+	words, _ := completion.Query(c.exe, args)
+	if err := c.db.PutCompletion(c.sha, args, words); err != nil {
+		_ = err // best-effort
+	}
+	return words
+}
+
+// isCommandWord filters completions down to plausible subcommand names;
+// completions for positional args (PR numbers, file names) are not.
+func isCommandWord(w string) bool {
+	if w == "" || w[0] < 'a' || w[0] > 'z' {
+		return false
+	}
+	for _, r := range w {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
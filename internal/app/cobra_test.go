@@ -0,0 +1,118 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+// kubeTool answers cobra's __complete protocol (the comment carries the
+// markers completion.IsCobra looks for) and logs every query to $COMPLETE_LOG.
+const kubeTool = `#!/bin/sh
+# __completeNoDesc ShellCompDirective
+if [ "$1" = __complete ]; then
+  shift
+  echo "[$*]" >> "$COMPLETE_LOG"
+  case "$*" in
+    "") printf 'config\tModify kubeconfig\nget\tDisplay resources\n:4\n' ;;
+    "config ") printf 'view\tDisplay merged kubeconfig\nuse-context\tSet the current-context\n:4\n' ;;
+    "config view -") printf -- '--output\tOutput format\n-o\tOutput format\n--raw\tDisplay raw data\n:4\n' ;;
+    *) printf ':4\n' ;;
+  esac
+  exit 0
+fi
+echo "error: unknown command or flag" >&2
+exit 1
+`
+
+func TestRunShim_CobraCompletionFixesNestedSubcommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	setTempHomeAndCWD(t)
+	t.Setenv("ACKCHYUALLY_TEST_FORCE_TTY", "true")
+	tmp := t.TempDir()
+	writeExec(t, tmp, "kubectl", kubeTool, "")
+	completeLog := filepath.Join(tmp, "complete.log")
+	t.Setenv("COMPLETE_LOG", completeLog)
+	t.Setenv("PATH", tmp+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	for i := 0; i < 3; i++ {
+		_, _, errOut := captureStdoutStderr(t, func() int {
+			return RunShim("kubectl", []string{"config", "viwe", "--ouptut=json"})
+		})
+		if i == 0 {
+			// Completions are queried in the background, for the next failure.
+			if strings.Contains(errOut, "kubectl completions") {
+				t.Fatalf("first run stderr = %q, want no completion-based suggestion yet", errOut)
+			}
+			continue
+		}
+		want := "ackchyually: suggestion (patched using kubectl completions):\n" +
+			"  kubectl config view --output=json\n"
+		if !strings.Contains(errOut, want) {
+			t.Fatalf("run %d: stderr = %q, want it to contain %q", i, errOut, want)
+		}
+		if !strings.Contains(errOut, "fixes viwe→view, --ouptut→--output; valid per kubectl completions") {
+			t.Fatalf("run %d: stderr missing reasons:\n%s", i, errOut)
+		}
+	}
+
+	// Each query ran once; later failures were served from the cache.
+	b, err := os.ReadFile(completeLog)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if got := strings.Split(strings.TrimSpace(string(b)), "\n"); len(got) != 3 {
+		t.Fatalf("__complete queries = %q, want 3", got)
+	}
+}
+
+func TestVocabCacheIsCobra_WaitsForIdentity(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	setTempHomeAndCWD(t)
+	tmp := t.TempDir()
+	writeExec(t, tmp, "kubectl", kubeTool, "")
+	exe := filepath.Join(tmp, "kubectl")
+
+	if err := store.WithDB(func(db *store.DB) error {
+		c := &vocabCache{db: db, exe: exe, sha: "kubesha", probe: true}
+		if c.isCobra() {
+			t.Error("isCobra before the binary is identified = true")
+		}
+		if _, err := db.UpsertTool(store.ToolIdentity{ExePath: exe, SHA256: "kubesha"}); err != nil {
+			return err
+		}
+		cached := &vocabCache{db: db, exe: exe, sha: "kubesha"}
+		if cached.isCobra() || !cached.missed {
+			t.Error("isCobra without probing = true or not noted as missed")
+		}
+		if !c.isCobra() {
+			t.Error("isCobra once identified = false")
+		}
+		kind, err := db.ToolCompletion("kubesha")
+		if err != nil || kind != store.CompletionCobra {
+			t.Errorf("ToolCompletion = %q, %v; want the answer kept", kind, err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsCommandWord(t *testing.T) {
+	for w, want := range map[string]bool{
+		"view": true, "use-context": true, "api2": true,
+		"123": false, "--raw": false, "Makefile": false, "a.txt": false, "": false,
+	} {
+		if got := isCommandWord(w); got != want {
+			t.Errorf("isCommandWord(%q) = %v, want %v", w, got, want)
+		}
+	}
+}
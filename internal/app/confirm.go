@@ -0,0 +1,159 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/joelklabo/ackchyually/internal/config"
+	"github.com/joelklabo/ackchyually/internal/execx"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+func autoExecMode() string {
+	return strings.TrimSpace(strings.ToLower(os.Getenv("ACKCHYUALLY_AUTO_EXEC")))
+}
+
+func autoExecConfirmEnabled() bool {
+	return autoExecMode() == "confirm"
+}
+
+// confirmAndRun implements ACKCHYUALLY_AUTO_EXEC=confirm: show the top
+// suggestion as a diff against what was typed and ask before running it.
+// Corrections accepted cfg.AutoExec.Promote() times in a row run without
+// asking. handled is false when there was no suggestion to offer or no
+// terminal to ask on; otherwise code is the exit code to return (failedCode
+// if the user declined).
+func confirmAndRun(tool, ctxKey string, argvSafe []string, hints usageHints, failedCode int) (code int, handled bool) {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.Config{}
+	}
+
+	var top Suggestion
+	var fixed []string
+	var streak int
+	if err := store.WithDB(func(db *store.DB) error {
+		sugs, err := rankSuggestions(db, tool, ctxKey, argvSafe, hints, 1)
+		if err != nil || len(sugs) == 0 {
+			return err
+		}
+		top, fixed = sugs[0], sugs[0].Argv
+		streak, err = db.AcceptStreak(ctxKey, tool, argvSafe, fixed)
+		return err
+	}); err != nil || len(fixed) == 0 {
+		return 0, false
+	}
+
+	record := func(choice string) {
+		if err := store.WithDB(func(db *store.DB) error {
+			return db.RecordConfirmChoice(store.ConfirmChoice{
+				At:         time.Now(),
+				ContextKey: ctxKey,
+				Tool:       tool,
+				FailedArgv: argvSafe,
+				FixedArgv:  fixed,
+				Choice:     choice,
+			})
+		}); err != nil {
+			_ = err // best-effort
+		}
+	}
+
+	if n := cfg.AutoExec.Promote(); n > 0 && streak >= n {
+		record(store.ChoiceAuto)
+		fmt.Fprintf(os.Stderr, "ackchyually: auto-exec (accepted %d× before):\n", streak)
+		fmt.Fprintln(os.Stderr, "  "+execx.ShellJoin(hints.command(fixed)))
+		return rerun(tool, hints.Exe, fixed), true
+	}
+
+	// Without a terminal to answer on, the plain suggestions are shown.
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return 0, false
+	}
+	fmt.Fprintln(os.Stderr, suggestionHeader(top))
+	fmt.Fprint(os.Stderr, argvDiff(hints.command(argvSafe), hints.command(fixed)))
+
+	restore, err := execx.MakeRaw(os.Stdin)
+	if err != nil {
+		return 0, false
+	}
+	wait := func(d time.Duration) bool { return execx.WaitReadable(os.Stdin, d) }
+	choice := confirmPrompt(os.Stdin, wait, os.Stderr, cfg.AutoExec.Timeout())
+	if choice != store.ChoiceEdit {
+		restore()
+	}
+	record(choice)
+
+	switch choice {
+	case store.ChoiceYes:
+		return rerun(tool, hints.Exe, fixed), true
+	case store.ChoiceEdit:
+		p := &picker{
+			title:    "ackchyually: edit (enter run, esc cancel):",
+			items:    []pickItem{{Cmd: execx.ShellJoin(hints.command(fixed))}},
+			editing:  true,
+			editOnly: true,
+			edit:     []rune(execx.ShellJoin(hints.command(fixed))),
+		}
+		cmd, ok := p.run(os.Stdin, os.Stderr)
+		restore()
+		if !ok {
+			return failedCode, true
+		}
+		if code, ran := runEdited(tool, hints, cmd); ran {
+			return code, true
+		}
+	}
+	return failedCode, true
+}
+
+// confirmPrompt asks [Y/n/e(dit)] on out and reads single key presses from
+// in (a terminal in raw mode). wait reports whether in has input within the
+// given time. Enter means yes; no answer within timeout means no.
+func confirmPrompt(in io.Reader, wait func(time.Duration) bool, out io.Writer, timeout time.Duration) string {
+	fmt.Fprintf(out, "ackchyually: run it? [Y/n/e(dit)] (%s) ", timeout.Round(time.Second))
+	answer := func(choice, echo string) string {
+		fmt.Fprint(out, echo+"\r\n")
+		return choice
+	}
+
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 16)
+	for {
+		left := time.Until(deadline)
+		if left <= 0 || !wait(left) {
+			return answer(store.ChoiceTimeout, "(timed out)")
+		}
+		n, err := in.Read(buf)
+		for _, k := range splitKeys(buf[:n]) {
+			switch k {
+			case "y", "Y", "\r", "\n":
+				return answer(store.ChoiceYes, "y")
+			case "n", "N", "\x1b", "\x03", "q":
+				return answer(store.ChoiceNo, "n")
+			case "e", "E":
+				return answer(store.ChoiceEdit, "e")
+			}
+		}
+		if err != nil {
+			return answer(store.ChoiceNo, "n")
+		}
+	}
+}
+
+// argvDiff renders what was typed and the suggestion as a -/+ pair.
+func argvDiff(typed, fixed []string) string {
+	return "  - " + execx.ShellJoin(typed) + "\n" +
+		"  + " + execx.ShellJoin(fixed) + "\n"
+}
+
+// confirmEnabled reports whether to prompt; agent is who ran the command
+// (detectAgent), and agents are never prompted.
+func confirmEnabled(agent string) bool {
+	return autoExecConfirmEnabled() && agent == "" && execx.IsTTY() && term.IsTerminal(int(os.Stderr.Fd()))
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+func TestConfirmPrompt(t *testing.T) {
+	ready := func(time.Duration) bool { return true }
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"y", store.ChoiceYes},
+		{"\r", store.ChoiceYes},
+		{"n", store.ChoiceNo},
+		{"\x1b", store.ChoiceNo},
+		{"e", store.ChoiceEdit},
+		{"xzy", store.ChoiceYes}, // unknown keys are ignored
+		{"", store.ChoiceNo},     // EOF
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if got := confirmPrompt(strings.NewReader(tt.input), ready, &out, time.Second); got != tt.want {
+			t.Errorf("confirmPrompt(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+		if !strings.Contains(out.String(), "[Y/n/e(dit)]") {
+			t.Errorf("prompt missing choices: %q", out.String())
+		}
+	}
+
+	var out bytes.Buffer
+	never := func(time.Duration) bool { return false }
+	if got := confirmPrompt(strings.NewReader("y"), never, &out, 10*time.Millisecond); got != store.ChoiceTimeout {
+		t.Fatalf("confirmPrompt(no input) = %q, want timeout", got)
+	}
+	if !strings.Contains(out.String(), "timed out") {
+		t.Fatalf("timeout not reported: %q", out.String())
+	}
+}
+
+func TestArgvDiff(t *testing.T) {
+	got := argvDiff([]string{"git", "log", "--prety=oneline"}, []string{"git", "log", "--pretty=oneline"})
+	want := "  - git log --prety=oneline\n  + git log --pretty=oneline\n"
+	if got != want {
+		t.Fatalf("argvDiff = %q, want %q", got, want)
+	}
+}
+
+func TestConfirmEnabled_RequiresModeAndTerminal(t *testing.T) {
+	t.Setenv("ACKCHYUALLY_AUTO_EXEC", "known_success")
+	if autoExecConfirmEnabled() {
+		t.Fatal("confirm enabled for known_success")
+	}
+	t.Setenv("ACKCHYUALLY_AUTO_EXEC", " Confirm ")
+	if !autoExecConfirmEnabled() {
+		t.Fatal("confirm not enabled for ACKCHYUALLY_AUTO_EXEC=confirm")
+	}
+	if confirmEnabled("") {
+		t.Fatal("confirm must not prompt without a terminal")
+	}
+}
+
+func TestConfirmAndRun_NoPromptWithoutTerminal(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	seedInvocation(t, ctxKey, "git", []string{"git", "log", "--pretty=oneline"}, time.Now().Add(-time.Hour), 0)
+
+	// Stdin is not a terminal under go test.
+	var handled bool
+	_, _, errOut := captureStdoutStderr(t, func() int {
+		_, handled = confirmAndRun("git", ctxKey, []string{"git", "log", "--prety=oneline"}, usageHints{}, 129)
+		return 0
+	})
+	if handled || errOut != "" {
+		t.Fatalf("confirmAndRun handled=%v stderr=%q; want the plain suggestions left to the caller", handled, errOut)
+	}
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+// correctionWindow bounds how long after a usage failure a success still
+// counts as its fix.
+const correctionWindow = 5 * time.Minute
+
+// learnCorrection links the most recent usage failure of the same tool and
+// subcommand in ctxKey to the success argvSafe. It must run before the
+// success itself is recorded.
+func learnCorrection(db *store.DB, ctxKey, tool string, argvSafe []string, at time.Time) error {
+	if len(argvSafe) == 0 || containsRedacted(argvSafe) {
+		return nil
+	}
+	recent, err := db.ListInvocations(ctxKey, tool, 20)
+	if err != nil {
+		return err
+	}
+	p := profile.Load(tool)
+	sub := subcommandOf(p, argvSafe)
+	for _, inv := range recent {
+		if at.Sub(inv.At) > correctionWindow {
+			return nil
+		}
+		var argv []string
+		if err := json.Unmarshal([]byte(inv.ArgvJSON), &argv); err != nil || len(argv) == 0 {
+			continue
+		}
+		if !sameSubcommand(sub, subcommandOf(p, argv)) {
+			continue
+		}
+		if inv.Class != profile.ClassUsage {
+			if inv.ExitCode == 0 {
+				return nil // the failure before this was already fixed
+			}
+			continue
+		}
+		if slicesEqual(argv, argvSafe) {
+			return nil
+		}
+		return db.RecordCorrection(store.Correction{
+			ContextKey: ctxKey,
+			Tool:       tool,
+			Subcommand: sub,
+			FailedArgv: argv,
+			FixedArgv:  argvSafe,
+			Last:       at,
+		})
+	}
+	return nil
+}
+
+// subcommandOf returns the first positional argument after the tool name,
+// per p's global_value_flags.
+func subcommandOf(p profile.Profile, argv []string) string {
+	if i := p.SubcommandIndex(argv); i > 0 {
+		return argv[i]
+	}
+	return ""
+}
+
+// sameSubcommand tolerates a one-edit typo so "git comit" can be linked to
+// "git commit".
+func sameSubcommand(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	return a == b || fuzzyTokenMatch(a, b)
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+func TestRunShim_SuggestsLearnedCorrection(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	tmp := t.TempDir()
+	writeExec(t, tmp, "script",
+		"#!/bin/sh\ncase \"$*\" in *vrbose*) echo 'unknown flag: --vrbose' >&2; exit 2;; esac\nexit 0",
+		"@echo off\r\necho %* | findstr vrbose >nul && (echo unknown flag: --vrbose 1>&2 & exit /b 2)\r\nexit /b 0")
+	t.Setenv("PATH", tmp+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// Similarity alone would pick this one.
+	for i := 0; i < 5; i++ {
+		seedInvocation(t, ctxKey, "script", []string{"script", "build", "--verbose", "--race"}, time.Now().Add(-time.Hour), 0)
+	}
+
+	captureStdoutStderr(t, func() int { return RunShim("script", []string{"build", "--vrbose"}) })
+	captureStdoutStderr(t, func() int { return RunShim("script", []string{"build", "-v"}) })
+
+	code, _, errOut := captureStdoutStderr(t, func() int {
+		return RunShim("script", []string{"build", "--vrbose"})
+	})
+	if code != 2 {
+		t.Fatalf("RunShim returned %d want 2", code)
+	}
+	if !strings.Contains(errOut, "what fixed this last time") || !strings.Contains(errOut, "  script build -v\n") {
+		t.Fatalf("stderr missing learned correction:\n%s", errOut)
+	}
+}
+
+func TestLearnCorrection(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	now := time.Now()
+	insert := func(argv []string, at time.Time, code int, class string) {
+		t.Helper()
+		if err := store.WithDB(func(db *store.DB) error {
+			return db.InsertInvocation(store.Invocation{
+				At: at, ContextKey: ctxKey, Tool: argv[0], ArgvJSON: store.MustJSON(argv),
+				ExitCode: code, Mode: "pipes", Class: class,
+			})
+		}); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	lookup := func(failed []string) (store.Correction, bool) {
+		t.Helper()
+		var c store.Correction
+		var ok bool
+		if err := store.WithDB(func(db *store.DB) error {
+			var err error
+			c, ok, err = db.LookupCorrection(ctxKey, failed[0], failed)
+			return err
+		}); err != nil {
+			t.Fatalf("LookupCorrection: %v", err)
+		}
+		return c, ok
+	}
+	learn := func(argv []string, at time.Time) {
+		t.Helper()
+		if err := store.WithDB(func(db *store.DB) error {
+			return learnCorrection(db, ctxKey, argv[0], argv, at)
+		}); err != nil {
+			t.Fatalf("learnCorrection: %v", err)
+		}
+	}
+
+	// Typo'd subcommand, fixed 20s later.
+	insert([]string{"git", "comit", "-m", "x"}, now.Add(-20*time.Second), 1, profile.ClassUsage)
+	learn([]string{"git", "commit", "-m", "x"}, now)
+	if c, ok := lookup([]string{"git", "comit", "-m", "x"}); !ok || c.Subcommand != "commit" || strings.Join(c.FixedArgv, " ") != "git commit -m x" {
+		t.Fatalf("correction = %+v ok=%v", c, ok)
+	}
+
+	// A flag's value is not the subcommand.
+	insert([]string{"git", "-C", "repo", "lgo"}, now.Add(-20*time.Second), 1, profile.ClassUsage)
+	learn([]string{"git", "-C", "repo", "log"}, now)
+	if c, ok := lookup([]string{"git", "-C", "repo", "lgo"}); !ok || c.Subcommand != "log" {
+		t.Fatalf("correction with -C = %+v ok=%v", c, ok)
+	}
+
+	// Outside the window.
+	insert([]string{"git", "log", "--prety"}, now.Add(-10*time.Minute), 129, profile.ClassUsage)
+	learn([]string{"git", "log", "--pretty=oneline"}, now)
+	if _, ok := lookup([]string{"git", "log", "--prety"}); ok {
+		t.Fatal("failure outside the window should not be linked")
+	}
+
+	// Different subcommand.
+	insert([]string{"git", "psuh", "--force"}, now.Add(-5*time.Second), 1, profile.ClassUsage)
+	learn([]string{"git", "status"}, now)
+	if _, ok := lookup([]string{"git", "psuh", "--force"}); ok {
+		t.Fatal("success of another subcommand should not be linked")
+	}
+
+	// Failure already followed by a success.
+	insert([]string{"go", "tset"}, now.Add(-30*time.Second), 2, profile.ClassUsage)
+	insert([]string{"go", "test"}, now.Add(-20*time.Second), 0, profile.ClassOK)
+	learn([]string{"go", "test", "./..."}, now)
+	if c, ok := lookup([]string{"go", "tset"}); ok {
+		t.Fatalf("already-fixed failure linked again: %+v", c)
+	}
+}
+
+func TestSuggestKnownGood_PrintsConfiguredCount(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	seedInvocation(t, ctxKey, "git", []string{"git", "log", "--oneline"}, time.Now().Add(-time.Hour), 0)
+	seedInvocation(t, ctxKey, "git", []string{"git", "log", "--pretty=oneline"}, time.Now(), 0)
+	seedInvocation(t, ctxKey, "git", []string{"git", "log", "-n", "5"}, time.Now(), 0)
+
+	_, _, errOut := captureStdoutStderr(t, func() int {
+		suggestKnownGood("git", ctxKey, []string{"git", "log", "--prety=oneline"}, usageHints{})
+		return 0
+	})
+	if !strings.Contains(errOut, "  git log --pretty=oneline\n") || !strings.Contains(errOut, "fixes --prety→--pretty; used 1×") {
+		t.Fatalf("stderr missing top suggestion with reason:\n%s", errOut)
+	}
+	if !strings.Contains(errOut, "  git log --oneline\n") || !strings.Contains(errOut, "  git log -n 5\n") {
+		t.Fatalf("stderr missing alternatives:\n%s", errOut)
+	}
+
+	cfg := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, cfg, "[suggest]\ncount = 1\n", 0o600)
+	t.Setenv("ACKCHYUALLY_CONFIG", cfg)
+	_, _, errOut = captureStdoutStderr(t, func() int {
+		suggestKnownGood("git", ctxKey, []string{"git", "log", "--prety=oneline"}, usageHints{})
+		return 0
+	})
+	if strings.Contains(errOut, "--oneline\n") {
+		t.Fatalf("suggest.count=1 should print a single suggestion:\n%s", errOut)
+	}
+}
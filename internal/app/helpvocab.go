@@ -0,0 +1,133 @@
+package app
+
+import (
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/config"
+	"github.com/joelklabo/ackchyually/internal/helptext"
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+	"github.com/joelklabo/ackchyually/internal/toolid"
+)
+
+// Tool vocabulary sources (Suggestion.Source).
+const (
+	vocabCompletions = "completions"
+	vocabHelp        = "help"
+)
+
+// vocabCache is a tool binary's cached vocabulary (its --help text and
+// completions). Only with probe set is the tool run to fill in what is
+// missing; otherwise a miss is noted in missed and answered as empty, so the
+// shim's failure path never waits on the tool (see probeQueuedVocab).
+type vocabCache struct {
+	db       *store.DB
+	exe, sha string
+	probe    bool
+	missed   bool
+}
+
+// toolVocabulary asks the tool itself what it accepts, so typos can be fixed
+// before there is any history: cobra's `__complete` when the binary supports
+// it (exact, including nested subcommands), otherwise its --help text.
+// Everything is cached per tool binary (sha).
+func toolVocabulary(c *vocabCache, failed []string) (v tokenVocab, source string) {
+	if c.exe == "" || c.sha == "" {
+		return tokenVocab{SubAt: -1}, ""
+	}
+	if cfg, err := config.Load(); err == nil && cfg.Suggest.NoHelpText {
+		return tokenVocab{SubAt: -1}, ""
+	}
+	if v, ok := cobraVocabulary(c, failed); ok {
+		return v, vocabCompletions
+	}
+	return helpVocabulary(c, failed), vocabHelp
+}
+
+// helpVocabulary returns the flags and subcommands that `exe --help` (and
+// `exe <sub> --help` for the subcommand in failed) list. Each help text is run
+// at most once per tool version.
+func helpVocabulary(c *vocabCache, failed []string) tokenVocab {
+	v := tokenVocab{Flags: map[string]int{}, Subs: map[string]int{}, SubAt: profile.Load(failed[0]).SubcommandIndex(failed)}
+
+	root := c.help("")
+	for _, s := range root.Subcommands {
+		v.Subs[s] = 1
+	}
+	for _, f := range root.Flags {
+		v.Flags[f] = 1
+	}
+
+	// Only subcommands the help text itself lists are ever passed to the tool.
+	if v.SubAt > 0 {
+		sub := failed[v.SubAt]
+		if v.Subs[sub] == 0 {
+			sub, _ = closestToken(sub, v.Subs)
+		}
+		if sub != "" {
+			for _, f := range c.help(sub).Flags {
+				v.Flags[f] = 1
+			}
+		}
+	}
+	return v
+}
+
+func (c *vocabCache) help(sub string) store.HelpVocab {
+	if v, ok, err := c.db.GetHelpVocab(c.sha, sub); err == nil && ok {
+		return v
+	}
+	if !c.probe {
+		c.missed = true
+		return store.HelpVocab{}
+	}
+	parsed := helptext.Fetch(c.exe, sub)
+	v := store.HelpVocab{ToolSHA: c.sha, Subcommand: sub, Subcommands: parsed.Subcommands, Flags: parsed.Flags}
+	if err := c.db.PutHelpVocab(v); err != nil {
+		_ = err // best-effort
+	}
+	return v
+}
+
+// queueVocabProbe queues argvSafe for probeQueuedVocab when the tool's
+// vocabulary for it isn't cached yet, or the binary isn't identified yet (sha
+// empty). It reports whether the background identifier should be started.
+// With ACKCHYUALLY_IDENTIFY=sync the tool is asked right away instead.
+func queueVocabProbe(db *store.DB, exe, sha string, argvSafe []string) (bool, error) {
+	if cfg, err := config.Load(); err == nil && cfg.Suggest.NoHelpText {
+		return false, nil
+	}
+	if sha != "" {
+		c := &vocabCache{db: db, exe: exe, sha: sha, probe: identifyInline()}
+		if toolVocabulary(c, argvSafe); !c.missed {
+			return false, nil
+		}
+	}
+	return db.QueueVocabProbe(exe, argvSafe, time.Now())
+}
+
+// probeQueuedVocab runs in the background identifier, after the queued
+// binaries are identified: it asks each queued command's tool what it
+// accepts, so the next failure finds it cached.
+func probeQueuedVocab() error {
+	var probes []store.VocabProbe
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		probes, err = db.QueuedVocabProbes()
+		return err
+	}); err != nil {
+		return err
+	}
+	for _, p := range probes {
+		ti, known := toolid.Lookup(p.ExePath)
+		if err := store.WithDB(func(db *store.DB) error {
+			if known {
+				toolVocabulary(&vocabCache{db: db, exe: p.ExePath, sha: ti.SHA256, probe: true}, p.Argv)
+			}
+			return db.FinishVocabProbe(p)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+// cobraTool prints cobra-style help and fails with "unknown command"/"unknown
+// flag" otherwise. Every --help run is appended to $HELP_LOG.
+const cobraTool = `#!/bin/sh
+case "$*" in
+  --help)
+    echo "$*" >> "$HELP_LOG"
+    printf 'Usage:\n  mytool [command]\n\nAvailable Commands:\n  deploy      Deploy it\n  status      Show status\n\nFlags:\n  -h, --help   help for mytool\n'
+    exit 0 ;;
+  "status --help")
+    echo "$*" >> "$HELP_LOG"
+    printf 'Usage:\n  mytool status [flags]\n\nFlags:\n      --pretty string   output format\n  -h, --help            help for status\n'
+    exit 0 ;;
+  "status --pretty=short") echo ok; exit 0 ;;
+  status*) echo "Error: unknown flag: $2" >&2; exit 1 ;;
+  *) echo "Error: unknown command \"$1\" for \"mytool\"" >&2; exit 1 ;;
+esac
+`
+
+func TestRunShim_UsesHelpVocabularyOnceCached(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	setTempHomeAndCWD(t)
+	t.Setenv("ACKCHYUALLY_TEST_FORCE_TTY", "true")
+	tmp := t.TempDir()
+	writeExec(t, tmp, "mytool", cobraTool, "")
+	helpLog := filepath.Join(tmp, "help.log")
+	t.Setenv("HELP_LOG", helpLog)
+	t.Setenv("PATH", tmp+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// The first failure only queues the tool's help for the background
+	// identifier (in-process in tests): suggestions never wait on the tool.
+	code, _, errOut := captureStdoutStderr(t, func() int {
+		return RunShim("mytool", []string{"stauts", "--pretty=short"})
+	})
+	if code != 1 {
+		t.Fatalf("RunShim = %d, want 1", code)
+	}
+	if strings.Contains(errOut, "patched using mytool --help") {
+		t.Fatalf("first run stderr = %q, want no help-based suggestion yet", errOut)
+	}
+
+	_, _, errOut = captureStdoutStderr(t, func() int {
+		return RunShim("mytool", []string{"stauts", "--pretty=short"})
+	})
+	want := "ackchyually: suggestion (patched using mytool --help):\n" +
+		"  mytool status --pretty=short\n" +
+		"    mytool [-stauts-]{+status+} --pretty=short\n" +
+		"    keeps the rest of what you typed; fixes stauts→status; valid per mytool --help\n"
+	if !strings.Contains(errOut, want) {
+		t.Fatalf("stderr = %q, want it to contain %q", errOut, want)
+	}
+
+	// Subcommand flags come from `mytool status --help`; the cached root
+	// vocabulary is reused.
+	_, _, errOut = captureStdoutStderr(t, func() int {
+		return RunShim("mytool", []string{"status", "--prety=short"})
+	})
+	if !strings.Contains(errOut, "  mytool status --pretty=short\n") {
+		t.Fatalf("second run stderr:\n%s", errOut)
+	}
+	_, _, _ = captureStdoutStderr(t, func() int {
+		return RunShim("mytool", []string{"status", "--prety=long"})
+	})
+	b, err := os.ReadFile(helpLog)
+	if err != nil {
+		t.Fatalf("read help log: %v", err)
+	}
+	if got := strings.Fields(strings.ReplaceAll(string(b), "\n", ";")); strings.Join(got, " ") != "--help;status --help;" {
+		t.Fatalf("help runs = %q, want root and status once each", b)
+	}
+}
+
+func TestRunShim_NoHelpTextConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	setTempHomeAndCWD(t)
+	t.Setenv("ACKCHYUALLY_TEST_FORCE_TTY", "true")
+	tmp := t.TempDir()
+	writeExec(t, tmp, "mytool", cobraTool, "")
+	helpLog := filepath.Join(tmp, "help.log")
+	t.Setenv("HELP_LOG", helpLog)
+	t.Setenv("PATH", tmp+string(os.PathListSeparator)+os.Getenv("PATH"))
+	cfg := filepath.Join(tmp, "config.toml")
+	writeFile(t, cfg, "[suggest]\nno_help_text = true\n", 0o600)
+	t.Setenv("ACKCHYUALLY_CONFIG", cfg)
+
+	_, _, errOut := captureStdoutStderr(t, func() int {
+		return RunShim("mytool", []string{"stauts"})
+	})
+	if !strings.Contains(errOut, "no known-good mytool command saved") {
+		t.Fatalf("stderr = %q, want the no-history message", errOut)
+	}
+	if _, err := os.Stat(helpLog); !os.IsNotExist(err) {
+		t.Fatalf("help was run despite no_help_text (stat err %v)", err)
+	}
+}
+
+func TestRunShim_FailureNeverRunsToolHelp(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	setTempHomeAndCWD(t)
+	tmp := t.TempDir()
+	writeExec(t, tmp, "mytool", cobraTool, "")
+	helpLog := filepath.Join(tmp, "help.log")
+	t.Setenv("HELP_LOG", helpLog)
+	t.Setenv("PATH", tmp+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	started := 0
+	orig := identifyInBackground
+	identifyInBackground = func() { started++ }
+	t.Cleanup(func() { identifyInBackground = orig })
+
+	for i := 0; i < 2; i++ {
+		_, _, _ = captureStdoutStderr(t, func() int {
+			return RunShim("mytool", []string{"stauts"})
+		})
+	}
+	if _, err := os.Stat(helpLog); !os.IsNotExist(err) {
+		t.Fatalf("the shim ran mytool --help itself (stat err %v)", err)
+	}
+	if started != 1 {
+		t.Fatalf("background identifier started %d times, want once", started)
+	}
+	var probes []store.VocabProbe
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		probes, err = db.QueuedVocabProbes()
+		return err
+	}); err != nil || len(probes) != 1 || strings.Join(probes[0].Argv, " ") != "mytool stauts" {
+		t.Fatalf("queued probes = %+v, %v", probes, err)
+	}
+}
+
+func TestRunShim_IdentifySyncProbesInline(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	setTempHomeAndCWD(t)
+	t.Setenv("ACKCHYUALLY_TEST_FORCE_TTY", "true")
+	t.Setenv("ACKCHYUALLY_IDENTIFY", "sync")
+	tmp := t.TempDir()
+	writeExec(t, tmp, "mytool", cobraTool, "")
+	t.Setenv("HELP_LOG", filepath.Join(tmp, "help.log"))
+	t.Setenv("PATH", tmp+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	orig := identifyInBackground
+	identifyInBackground = func() { t.Error("sync mode started the background identifier") }
+	t.Cleanup(func() { identifyInBackground = orig })
+
+	_, _, errOut := captureStdoutStderr(t, func() int {
+		return RunShim("mytool", []string{"stauts"})
+	})
+	if !strings.Contains(errOut, "  mytool status\n") {
+		t.Fatalf("stderr = %q, want the help-based suggestion on the first failure", errOut)
+	}
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/joelklabo/ackchyually/internal/contextkey"
+	"github.com/joelklabo/ackchyually/internal/execx"
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+	"github.com/joelklabo/ackchyually/internal/ui"
+)
+
+func historyCmd(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	tool := fs.String("tool", "", "tool name (optional)")
+	limit := fs.Int("limit", 20, "max invocations to show")
+	tree := fs.Bool("tree", false, "show the tool calls each invocation made (see [lineage])")
+	if err := parseFlags(fs, args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: ackchyually history [--tool <tool>] [--limit <n>] [--tree]")
+		return 2
+	}
+	return historyImpl(*tool, *limit, *tree)
+}
+
+func historyImpl(tool string, limit int, tree bool) int {
+	ctxKey := contextkey.Detect()
+	u := ui.New(os.Stdout)
+
+	fmt.Printf("context: %s\n", ctxKey)
+	if reason, ok := detectOptOut(ctxKey); ok {
+		fmt.Printf("privacy: %s (%s); nothing is recorded here\n", u.Warn("opted out"), reason)
+	}
+	fmt.Println()
+
+	var invs []store.Invocation
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		if tree {
+			invs, err = db.ListInvocationTrees(ctxKey, tool, limit)
+		} else {
+			invs, err = db.ListInvocations(ctxKey, tool, limit)
+		}
+		return err
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "ackchyually:", err)
+		return 1
+	}
+
+	if len(invs) == 0 {
+		fmt.Println("(no invocations recorded)")
+		return 0
+	}
+	if tree {
+		printInvocationTrees(u, invs)
+		return 0
+	}
+	for _, inv := range invs {
+		fmt.Println(formatHistoryLine(u, inv, ""))
+	}
+	return 0
+}
+
+// printInvocationTrees prints each top-level invocation, newest first, with
+// the tool calls it made indented below it in the order they started. invs
+// is oldest first.
+func printInvocationTrees(u ui.UI, invs []store.Invocation) {
+	recorded := map[string]bool{}
+	for _, inv := range invs {
+		if inv.RunID != "" {
+			recorded[inv.RunID] = true
+		}
+	}
+	children := map[string][]store.Invocation{}
+	var roots []store.Invocation
+	for _, inv := range invs {
+		if recorded[inv.ParentRunID] {
+			children[inv.ParentRunID] = append(children[inv.ParentRunID], inv)
+			continue
+		}
+		roots = append(roots, inv)
+	}
+
+	var walk func(inv store.Invocation, indent, branch string)
+	walk = func(inv store.Invocation, indent, branch string) {
+		fmt.Println(formatHistoryLine(u, inv, indent+branch))
+		kids := children[inv.RunID]
+		switch branch {
+		case "├─ ":
+			indent += "│  "
+		case "└─ ":
+			indent += "   "
+		}
+		for i, kid := range kids {
+			next := "├─ "
+			if i == len(kids)-1 {
+				next = "└─ "
+			}
+			walk(kid, indent, next)
+		}
+	}
+	for i := len(roots) - 1; i >= 0; i-- {
+		walk(roots[i], "", "")
+	}
+}
+
+// formatHistoryLine renders one invocation; tree is drawn before the command.
+func formatHistoryLine(u ui.UI, inv store.Invocation, tree string) string {
+	command := execx.ShellJoin(invocationArgv(inv))
+	if tree != "" {
+		command = u.Dim(tree) + command
+	}
+	class := orDash(inv.Class)
+	status := u.OK(fmt.Sprintf("exit=%-3d %-11s", inv.ExitCode, class))
+	switch {
+	case inv.Class == profile.ClassInterrupted:
+		// Neither a failure nor a success.
+		status = u.Warn(fmt.Sprintf("exit=%-3d %-11s", inv.ExitCode, class))
+	case inv.ExitCode != 0 || inv.Class == profile.ClassUsage:
+		status = u.Error(fmt.Sprintf("exit=%-3d %-11s", inv.ExitCode, class))
+	}
+	return fmt.Sprintf("%s  %s  %s  %s  %s  %s",
+		u.Dim(fmt.Sprintf("#%-5d", inv.ID)),
+		u.Dim(inv.At.Local().Format("2006-01-02 15:04:05")),
+		status,
+		u.Dim(fmt.Sprintf("%6dms", inv.DurationMS)),
+		u.Dim(formatResources(inv)),
+		command,
+	)
+}
+
+const resourcesFormat = "cpu %-7s rss %-6s out %-6s err %-6s"
+
+// formatResources renders CPU time, peak memory and output volume; blank for
+// runs recorded before they were measured.
+func formatResources(inv store.Invocation) string {
+	if inv.CPUUserMS+inv.CPUSysMS+inv.MaxRSSKB+inv.StdoutBytes+inv.StderrBytes == 0 {
+		return strings.Repeat(" ", len(fmt.Sprintf(resourcesFormat, "", "", "", "")))
+	}
+	return fmt.Sprintf(resourcesFormat,
+		formatMS(inv.CPUUserMS+inv.CPUSysMS), formatSize(inv.MaxRSSKB*1024),
+		formatSize(inv.StdoutBytes), formatSize(inv.StderrBytes))
+}
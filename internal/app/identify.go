@@ -0,0 +1,51 @@
+package app
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/joelklabo/ackchyually/internal/execx"
+	"github.com/joelklabo/ackchyually/internal/toolid"
+)
+
+// identifyCmdName is the hidden CLI command the shim starts in the background
+// to identify new tool binaries (toolid.IdentifyQueued).
+const identifyCmdName = "__identify-tools"
+
+// identifyInBackground starts a detached ackchyually that hashes and probes
+// the queued tools, and asks them for the vocabulary of queued failures, so
+// neither a new binary's first run nor a failure's suggestions are held up by
+// it. The shim doesn't wait for it. Tests replace it.
+var identifyInBackground = func() {
+	self, err := os.Executable()
+	if err != nil {
+		return
+	}
+	cmd := exec.Command(self, identifyCmdName) //nolint:gosec,noctx // outlives the shim on purpose
+	cmd.Args[0] = "ackchyually"                // self may be a copied shim named after a tool
+	cmd.Env = execx.SanitizedEnv()
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return
+	}
+	_ = cmd.Process.Release()
+}
+
+// identifyInline reports whether ACKCHYUALLY_IDENTIFY=sync asks for new
+// binaries to be identified before they run, as in CI where nothing should
+// outlive the command.
+func identifyInline() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("ACKCHYUALLY_IDENTIFY")), "sync")
+}
+
+// identifyQueuedCmd runs in the background: no output, nothing recorded.
+func identifyQueuedCmd() int {
+	if err := toolid.IdentifyQueued(); err != nil {
+		return 1
+	}
+	if err := probeQueuedVocab(); err != nil {
+		return 1
+	}
+	return 0
+}
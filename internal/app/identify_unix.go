@@ -0,0 +1,13 @@
+//go:build !windows
+
+package app
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach puts cmd in its own session, away from the terminal's signals.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -21,8 +21,10 @@ import (
 
 const agentCLIHintMinInterval = 24 * time.Hour
 
-func maybePrintAgentCLIHint(now time.Time) {
-	if !isAgentCLIHintTTY() {
+func maybePrintAgentCLIHint(now time.Time, agent string) {
+	// An agent is already going through the shims, and the tip is for people;
+	// with structured output, stderr is the tool's own (or the report's).
+	if agent != "" || !isAgentCLIHintTTY() || structuredOutputSet() {
 		return
 	}
 	maybePrintAgentCLIHintImpl(context.Background(), os.Stderr, now)
@@ -20,7 +20,7 @@ func TestAgentCLIHint_NonTTY_NoOutput(t *testing.T) {
 	t.Setenv("PATH", tmp)
 
 	_, _, errOut := captureStdoutStderr(t, func() int {
-		maybePrintAgentCLIHint(time.Unix(1000, 0))
+		maybePrintAgentCLIHint(time.Unix(1000, 0), "")
 		return 0
 	})
 
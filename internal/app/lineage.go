@@ -0,0 +1,59 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strings"
+
+	"github.com/joelklabo/ackchyually/internal/config"
+	"github.com/joelklabo/ackchyually/internal/execx"
+)
+
+// parentIDEnv carries the run id of the shimmed invocation a tool was started
+// by, so the shims of the tools it runs in turn record themselves as its
+// children.
+const parentIDEnv = "ACKCHYUALLY_PARENT_ID"
+
+// lineageEnabled reports whether nested tool calls are recorded.
+// ACKCHYUALLY_LINEAGE=1/0 overrides `enabled` under `[lineage]`.
+func lineageEnabled() bool {
+	switch strings.TrimSpace(strings.ToLower(os.Getenv("ACKCHYUALLY_LINEAGE"))) {
+	case "1", "true", "yes":
+		return true
+	case "0", "false", "no":
+		return false
+	}
+	cfg, err := config.Load()
+	return err == nil && cfg.Lineage.Enabled
+}
+
+// parentRunID is the run id of the invocation this one was started by, or ""
+// for a top-level call.
+func parentRunID() string {
+	return strings.TrimSpace(os.Getenv(parentIDEnv))
+}
+
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// runTool runs the real tool. With lineage on (and a run id) the shim dir
+// stays on its PATH and parentIDEnv names this run; otherwise nested calls
+// bypass the shims as usual.
+func runTool(exe string, args []string, runID string) (execx.Result, error) {
+	if runID == "" {
+		return execx.Run(exe, args)
+	}
+	env := make([]string, 0, len(os.Environ())+1)
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, parentIDEnv+"=") {
+			env = append(env, e)
+		}
+	}
+	return execx.RunEnv(exe, args, append(env, parentIDEnv+"="+runID))
+}
@@ -199,7 +199,7 @@ func TestAutoExecKnownSuccess_EarlyReturns(t *testing.T) {
 
 	ctxKey := "cwd:/tmp/repo"
 
-	if code, ok := autoExecKnownSuccess("git", ctxKey, []string{"git", "status"}); ok || code != 0 {
+	if code, ok := autoExecKnownSuccess("git", "", ctxKey, []string{"git", "status"}); ok || code != 0 {
 		t.Fatalf("expected no auto-exec when empty, got code=%d ok=%v", code, ok)
 	}
 
@@ -218,7 +218,7 @@ func TestAutoExecKnownSuccess_EarlyReturns(t *testing.T) {
 	}); err != nil {
 		t.Fatalf("seed invocation: %v", err)
 	}
-	if code, ok := autoExecKnownSuccess("git", ctxKey, []string{"git", "status"}); ok || code != 0 {
+	if code, ok := autoExecKnownSuccess("git", "", ctxKey, []string{"git", "status"}); ok || code != 0 {
 		t.Fatalf("expected no auto-exec when cmd==argvSafe, got code=%d ok=%v", code, ok)
 	}
 
@@ -237,7 +237,7 @@ func TestAutoExecKnownSuccess_EarlyReturns(t *testing.T) {
 	}); err != nil {
 		t.Fatalf("seed invocation (redacted): %v", err)
 	}
-	if code, ok := autoExecKnownSuccess("git", ctxKey, []string{"git", "--token", "<redacted>"}); ok || code != 0 {
+	if code, ok := autoExecKnownSuccess("git", "", ctxKey, []string{"git", "--token", "<redacted>"}); ok || code != 0 {
 		t.Fatalf("expected no auto-exec when candidate contains redacted, got code=%d ok=%v", code, ok)
 	}
 }
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"os"
+	"strings"
+
+	"github.com/joelklabo/ackchyually/internal/config"
+)
+
+// passthrough reports whether the shim for tool should get out of the way
+// entirely: ACKCHYUALLY_OFF=1 for every tool, or the tool listed under
+// `[passthrough]`. Config errors are ignored, as for opt-outs.
+func passthrough(tool string) bool {
+	if shimsOff() {
+		return true
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return false
+	}
+	name := strings.TrimSuffix(tool, ".exe")
+	for _, t := range cfg.Passthrough.Tools {
+		if t = strings.TrimSpace(t); t == tool || t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// shimsOff reports whether ACKCHYUALLY_OFF turns every shim into a plain exec.
+func shimsOff() bool {
+	switch strings.TrimSpace(strings.ToLower(os.Getenv("ACKCHYUALLY_OFF"))) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPassthrough(t *testing.T) {
+	setTempHomeAndCWD(t)
+	cfg := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(cfg, []byte("[passthrough]\ntools = [\"terraform\", \" ssh \"]\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("ACKCHYUALLY_CONFIG", cfg)
+	t.Setenv("ACKCHYUALLY_OFF", "")
+
+	tests := []struct {
+		off, tool string
+		want      bool
+	}{
+		{"", "git", false},
+		{"", "terraform", true},
+		{"", "ssh.exe", true},
+		{"0", "git", false},
+		{"1", "git", true},
+		{"yes", "terraform", true},
+	}
+	for _, tt := range tests {
+		t.Setenv("ACKCHYUALLY_OFF", tt.off)
+		if got := passthrough(tt.tool); got != tt.want {
+			t.Errorf("OFF=%q passthrough(%q) = %v, want %v", tt.off, tt.tool, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/joelklabo/ackchyually/internal/execx"
+)
+
+// execPassthrough replaces the shim with the real tool, keeping the pid, the
+// terminal and the signals as if the shim were not there. Tools it runs in
+// turn still bypass the shims. It only returns if the exec fails.
+func execPassthrough(tool, exe string, args []string) int {
+	err := syscall.Exec(exe, append([]string{tool}, args...), execx.SanitizedEnv()) //nolint:gosec
+	fmt.Fprintln(os.Stderr, "ackchyually:", err)
+	return 126
+}
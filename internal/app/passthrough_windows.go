@@ -0,0 +1,32 @@
+//go:build windows
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/joelklabo/ackchyually/internal/execx"
+)
+
+// execPassthrough runs the real tool on the shim's own console and handles,
+// with nothing recorded. Windows has no exec, so the shim waits for it.
+func execPassthrough(_, exe string, args []string) int {
+	cmd := exec.CommandContext(context.Background(), exe, args...)
+	cmd.Env = execx.SanitizedEnv()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err := cmd.Run()
+	var ee *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &ee):
+		return ee.ExitCode()
+	default:
+		fmt.Fprintln(os.Stderr, "ackchyually:", err)
+		return 126
+	}
+}
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+	"github.com/joelklabo/ackchyually/internal/ui"
+)
+
+// patchArgv fixes only the offending tokens of a failed command, keeping
+// everything else the user typed (paths, counts, flag values). Offending
+// tokens come from the usage error line when it names them, otherwise from
+// any flag or subcommand that is one edit away from one seen in cands.
+// ok is false when nothing could be patched.
+func patchArgv(failed []string, usageLine string, cands []store.SuccessCandidate) (patched []string, fixes []TokenFix, ok bool) {
+	if len(cands) == 0 {
+		return nil, nil, false
+	}
+	return patchWithVocab(failed, usageLine, vocabulary(cands, failed))
+}
+
+// tokenVocab is what a command accepts, from history, completions or help.
+type tokenVocab struct {
+	Flags map[string]int // flag name → weight
+	Subs  map[string]int // valid tokens at argv[SubAt] → weight
+	SubAt int            // index of the (sub)command token to check; -1 for none
+}
+
+// patchWithVocab is patchArgv against an explicit vocabulary.
+func patchWithVocab(failed []string, usageLine string, v tokenVocab) (patched []string, fixes []TokenFix, ok bool) {
+	flags, subs, sub := v.Flags, v.Subs, v.SubAt
+	if len(failed) < 2 || (len(flags) == 0 && len(subs) == 0) {
+		return nil, nil, false
+	}
+
+	offending := offendingArgs(failed, usageLine)
+	for i := range offending {
+		// Usage lines often echo valid parts too ("usage: git log ...").
+		if name, _ := splitFlag(failed[i]); flags[name] > 0 || (i == sub && subs[failed[i]] > 0) {
+			delete(offending, i)
+		}
+	}
+	if len(offending) == 0 {
+		offending = nil
+	}
+
+	patched = append([]string(nil), failed...)
+	for i := 1; i < len(failed); i++ {
+		a := failed[i]
+		var repl string
+		switch {
+		case strings.HasPrefix(a, "-"):
+			name, rest := splitFlag(a)
+			if flags[name] > 0 || (offending != nil && !offending[i]) || isCountFlag(name) {
+				continue
+			}
+			fix, found := closestToken(name, flags)
+			if !found {
+				continue
+			}
+			repl = fix + rest
+			fixes = append(fixes, TokenFix{From: name, To: fix})
+		case i == sub:
+			if subs[a] > 0 || (offending != nil && !offending[i]) {
+				continue
+			}
+			fix, found := closestToken(a, subs)
+			if !found {
+				continue
+			}
+			repl = fix
+			fixes = append(fixes, TokenFix{From: a, To: fix})
+		default:
+			continue
+		}
+		patched[i] = repl
+	}
+	if len(fixes) == 0 {
+		return nil, nil, false
+	}
+	return patched, fixes, true
+}
+
+// vocabulary counts the flag names and subcommands used by known-good
+// commands, checking failed's first positional argument against the latter.
+func vocabulary(cands []store.SuccessCandidate, failed []string) tokenVocab {
+	p := profile.Load(failed[0])
+	v := tokenVocab{Flags: map[string]int{}, Subs: map[string]int{}, SubAt: p.SubcommandIndex(failed)}
+	for _, c := range cands {
+		if len(c.Argv) < 2 || containsRedacted(c.Argv) {
+			continue
+		}
+		if i := p.SubcommandIndex(c.Argv); i > 0 {
+			v.Subs[c.Argv[i]] += c.Count
+		}
+		for _, a := range c.Argv[1:] {
+			if strings.HasPrefix(a, "-") && a != "-" && a != "--" {
+				name, _ := splitFlag(a)
+				v.Flags[name] += c.Count
+			}
+		}
+	}
+	return v
+}
+
+// offendingArgs marks the args named by the usage error line (e.g. "unknown
+// option `prety=%h'"). It returns nil when the line names none of them.
+func offendingArgs(argv []string, line string) map[int]bool {
+	line = strings.ToLower(line)
+	if line == "" {
+		return nil
+	}
+	var out map[int]bool
+	for i := 1; i < len(argv); i++ {
+		a := strings.ToLower(argv[i])
+		name, _ := splitFlag(a)
+		for _, t := range []string{a, name, strings.TrimLeft(a, "-"), strings.TrimLeft(name, "-")} {
+			if len(strings.TrimLeft(t, "-")) >= 2 && mentions(line, t) {
+				if out == nil {
+					out = map[int]bool{}
+				}
+				out[i] = true
+				break
+			}
+		}
+	}
+	return out
+}
+
+// mentions reports whether tok appears in line as a whole token (not as part
+// of a longer word).
+func mentions(line, tok string) bool {
+	for start := 0; ; {
+		i := strings.Index(line[start:], tok)
+		if i < 0 {
+			return false
+		}
+		i += start
+		j := i + len(tok)
+		if (i == 0 || !isTokenChar(line[i-1])) && (j == len(line) || !isTokenChar(line[j])) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+func isTokenChar(c byte) bool {
+	return c == '-' || c == '_' || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
+
+// closestToken picks the most used vocabulary token one edit away from tok.
+func closestToken(tok string, vocab map[string]int) (string, bool) {
+	best, bestN := "", 0
+	for v, n := range vocab {
+		if !fuzzyTokenMatch(strings.ToLower(tok), strings.ToLower(v)) {
+			continue
+		}
+		if n > bestN || (n == bestN && v < best) {
+			best, bestN = v, n
+		}
+	}
+	return best, bestN > 0
+}
+
+// isCountFlag reports whether a flag carries a number (-100, -n5): a count
+// the user chose, never a typo to patch.
+func isCountFlag(name string) bool {
+	if isAttachedNumericShortFlag(name) {
+		return true
+	}
+	n := strings.TrimLeft(name, "-")
+	return n != "" && strings.Trim(n, "0123456789") == ""
+}
+
+func splitFlag(a string) (name, rest string) {
+	if i := strings.IndexByte(a, '='); i > 0 {
+		return a[:i], a[i:]
+	}
+	return a, ""
+}
+
+// tokenDiff renders from → to as one line in git's --word-diff style:
+// unchanged tokens as is, replaced ones as [-old-]{+new+} (colored on a
+// terminal).
+func tokenDiff(u ui.UI, from, to []string) string {
+	var b strings.Builder
+	n := len(from)
+	if len(to) > n {
+		n = len(to)
+	}
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		switch {
+		case i >= len(from):
+			b.WriteString(u.OK("{+" + to[i] + "+}"))
+		case i >= len(to):
+			b.WriteString(u.Error("[-" + from[i] + "-]"))
+		case from[i] == to[i]:
+			b.WriteString(from[i])
+		default:
+			b.WriteString(u.Error("[-"+from[i]+"-]") + u.OK("{+"+to[i]+"+}"))
+		}
+	}
+	return b.String()
+}
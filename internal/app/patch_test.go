@@ -0,0 +1,145 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/store"
+	"github.com/joelklabo/ackchyually/internal/ui"
+)
+
+func TestPatchArgv(t *testing.T) {
+	cands := []store.SuccessCandidate{
+		{Argv: []string{"git", "log", "-1", "--pretty=%s"}, Count: 3},
+		{Argv: []string{"git", "status", "--short"}, Count: 1},
+		{Argv: []string{"git", "commit", "-m", "<redacted>"}, Count: 9},
+	}
+
+	cands = append(cands, store.SuccessCandidate{Argv: []string{"git", "log", "-1000", "-n5", "--oneline"}, Count: 2})
+
+	tests := []struct {
+		name      string
+		failed    []string
+		line      string
+		want      string
+		wantFixes string
+		wantOK    bool
+	}{
+		{
+			name:      "flag typo keeps value and other args",
+			failed:    []string{"git", "log", "-5", "--prety=%h", "src/foo.go"},
+			want:      "git log -5 --pretty=%h src/foo.go",
+			wantFixes: "--prety→--pretty",
+			wantOK:    true,
+		},
+		{
+			name:      "subcommand typo",
+			failed:    []string{"git", "stauts", "--short"},
+			want:      "git status --short",
+			wantFixes: "stauts→status",
+			wantOK:    true,
+		},
+		{
+			name:      "error line names the offending token",
+			failed:    []string{"git", "log", "--prety=%h", "--shrot"},
+			line:      "fatal: unrecognized argument: --prety=%h",
+			want:      "git log --pretty=%h --shrot",
+			wantFixes: "--prety→--pretty",
+			wantOK:    true,
+		},
+		{
+			name:      "usage line echoing the subcommand",
+			failed:    []string{"git", "log", "--prety=%h"},
+			line:      "usage: git log [<options>] [<revision-range>]",
+			want:      "git log --pretty=%h",
+			wantFixes: "--prety→--pretty",
+			wantOK:    true,
+		},
+		{
+			name:      "subcommand after a flag's value",
+			failed:    []string{"git", "-C", "repo", "stauts"},
+			want:      "git -C repo status",
+			wantFixes: "stauts→status",
+			wantOK:    true,
+		},
+		{
+			name:      "counts are kept",
+			failed:    []string{"git", "log", "-100", "-n50", "--prety=%h"},
+			want:      "git log -100 -n50 --pretty=%h",
+			wantFixes: "--prety→--pretty",
+			wantOK:    true,
+		},
+		{
+			name:   "redacted candidates are not vocabulary",
+			failed: []string{"git", "comit", "-m", "x"},
+		},
+		{
+			name:   "nothing close",
+			failed: []string{"git", "log", "--graph"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fixes, ok := patchArgv(tt.failed, tt.line, cands)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v (got %q)", ok, tt.wantOK, got)
+			}
+			if !ok {
+				return
+			}
+			if s := strings.Join(got, " "); s != tt.want {
+				t.Fatalf("patched = %q, want %q", s, tt.want)
+			}
+			var fs []string
+			for _, f := range fixes {
+				fs = append(fs, f.From+"→"+f.To)
+			}
+			if s := strings.Join(fs, ", "); s != tt.wantFixes {
+				t.Fatalf("fixes = %q, want %q", s, tt.wantFixes)
+			}
+		})
+	}
+}
+
+func TestOffendingArgs(t *testing.T) {
+	argv := []string{"git", "log", "-5", "--prety=%h", "src/foo.go"}
+	got := offendingArgs(argv, "error: unknown option `prety=%h'")
+	if len(got) != 1 || !got[3] {
+		t.Fatalf("offendingArgs = %v, want only index 3", got)
+	}
+	if got := offendingArgs(argv, "usage: git [<options>]"); got != nil {
+		t.Fatalf("offendingArgs(no mention) = %v, want nil", got)
+	}
+	// "log" inside "logging" is not a mention.
+	if got := offendingArgs(argv, "logging disabled"); got != nil {
+		t.Fatalf("offendingArgs(partial word) = %v, want nil", got)
+	}
+}
+
+func TestTokenDiff(t *testing.T) {
+	u := ui.New(nil)
+	got := tokenDiff(u, []string{"git", "log", "-5", "--prety=%h", "src/foo.go"}, []string{"git", "log", "-5", "--pretty=%h", "src/foo.go"})
+	want := "git log -5 [---prety=%h-]{+--pretty=%h+} src/foo.go"
+	if got != want {
+		t.Fatalf("tokenDiff = %q, want %q", got, want)
+	}
+}
+
+func TestSuggestKnownGood_PatchesInPlace(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	seedInvocation(t, ctxKey, "git", []string{"git", "log", "-1", "--pretty=%s"}, time.Now().Add(-time.Hour), 0)
+
+	_, _, errOut := captureStdoutStderr(t, func() int {
+		suggestKnownGood("git", ctxKey, []string{"git", "log", "-5", "--prety=%h", "src/foo.go"}, usageHints{Line: "error: unknown option `prety=%h'"})
+		return 0
+	})
+	want := "ackchyually: suggestion (patched from what you typed):\n" +
+		"  git log -5 --pretty=%h src/foo.go\n" +
+		"    git log -5 [---prety=%h-]{+--pretty=%h+} src/foo.go\n" +
+		"    keeps the rest of what you typed; fixes --prety→--pretty\n" +
+		"  git log -1 --pretty=%s\n"
+	if !strings.Contains(errOut, want) {
+		t.Fatalf("stderr = %q, want it to contain %q", errOut, want)
+	}
+}
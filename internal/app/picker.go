@@ -0,0 +1,262 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"github.com/joelklabo/ackchyually/internal/config"
+	"github.com/joelklabo/ackchyually/internal/execx"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+type pickItem struct {
+	Cmd string
+	Why string
+}
+
+type pickAction int
+
+const (
+	pickContinue pickAction = iota
+	pickRun
+	pickDismiss
+)
+
+// picker is the inline chooser shown after a usage failure when
+// [suggest] picker = true. It is driven one key at a time so it can be tested
+// without a terminal.
+type picker struct {
+	title   string
+	items   []pickItem
+	sel     int
+	editing bool
+	// editOnly makes Esc in edit mode dismiss instead of returning to the list.
+	editOnly bool
+	edit     []rune
+	drawn    int // lines drawn by the last render
+}
+
+func (p *picker) handle(key string) pickAction {
+	if p.editing {
+		switch key {
+		case "\r", "\n":
+			return pickRun
+		case "\x1b":
+			if p.editOnly {
+				return pickDismiss
+			}
+			p.editing = false
+			p.edit = nil
+		case "\x03":
+			return pickDismiss
+		case "\x7f", "\b":
+			if len(p.edit) > 0 {
+				p.edit = p.edit[:len(p.edit)-1]
+			}
+		case "\x15": // ctrl-u
+			p.edit = p.edit[:0]
+		default:
+			r, _ := utf8.DecodeRuneInString(key)
+			if r >= ' ' && r != utf8.RuneError && !strings.HasPrefix(key, "\x1b") {
+				p.edit = append(p.edit, []rune(key)...)
+			}
+		}
+		return pickContinue
+	}
+
+	switch key {
+	case "\x1b[A", "\x1bOA", "\x10", "k":
+		if p.sel > 0 {
+			p.sel--
+		}
+	case "\x1b[B", "\x1bOB", "\x0e", "j":
+		if p.sel < len(p.items)-1 {
+			p.sel++
+		}
+	case "\r", "\n":
+		return pickRun
+	case "e", "\t":
+		p.editing = true
+		p.edit = []rune(p.items[p.sel].Cmd)
+	case "\x1b", "\x03", "q":
+		return pickDismiss
+	}
+	return pickContinue
+}
+
+// choice is the command that Enter would run.
+func (p *picker) choice() string {
+	if p.editing {
+		return strings.TrimSpace(string(p.edit))
+	}
+	return p.items[p.sel].Cmd
+}
+
+func (p *picker) render(w io.Writer) {
+	var b strings.Builder
+	p.clear(&b)
+	title := p.title
+	if title == "" {
+		title = "ackchyually: suggestion (↑/↓ select, enter run, e edit, esc dismiss):"
+	}
+	lines := []string{title}
+	for i, it := range p.items {
+		mark := "  "
+		if i == p.sel {
+			mark = "> "
+		}
+		line := mark + it.Cmd
+		if it.Why != "" {
+			line += "  (" + it.Why + ")"
+		}
+		lines = append(lines, line)
+	}
+	if p.editing {
+		lines = append(lines, "  edit: "+string(p.edit)+"▏")
+	}
+	for _, l := range lines {
+		b.WriteString(l + "\r\n")
+	}
+	p.drawn = len(lines)
+	_, _ = io.WriteString(w, b.String())
+}
+
+// clear moves the cursor back to where the picker started and erases it.
+func (p *picker) clear(b *strings.Builder) {
+	if p.drawn > 0 {
+		fmt.Fprintf(b, "\x1b[%dA", p.drawn)
+	}
+	b.WriteString("\r\x1b[J")
+	p.drawn = 0
+}
+
+// runPicker reads keys from in (a terminal in raw mode) until the user runs
+// or dismisses. ok is false when dismissed.
+func runPicker(in io.Reader, out io.Writer, items []pickItem) (cmd string, ok bool) {
+	return (&picker{items: items}).run(in, out)
+}
+
+func (p *picker) run(in io.Reader, out io.Writer) (cmd string, ok bool) {
+	p.render(out)
+
+	buf := make([]byte, 256)
+	for {
+		n, err := in.Read(buf)
+		if n == 0 && err != nil {
+			return "", p.finish(out, false)
+		}
+		for _, key := range splitKeys(buf[:n]) {
+			switch p.handle(key) {
+			case pickRun:
+				cmd = p.choice()
+				if cmd == "" {
+					return "", p.finish(out, false)
+				}
+				return cmd, p.finish(out, true)
+			case pickDismiss:
+				return "", p.finish(out, false)
+			}
+		}
+		p.render(out)
+	}
+}
+
+func (p *picker) finish(out io.Writer, ok bool) bool {
+	var b strings.Builder
+	p.clear(&b)
+	_, _ = io.WriteString(out, b.String())
+	return ok
+}
+
+// splitKeys splits raw terminal input into key presses: CSI/SS3 sequences
+// (arrows), a lone ESC, or one UTF-8 character.
+func splitKeys(b []byte) []string {
+	var out []string
+	for len(b) > 0 {
+		if b[0] == 0x1b && len(b) >= 3 && (b[1] == '[' || b[1] == 'O') {
+			i := 2
+			for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+				i++
+			}
+			if i < len(b) {
+				i++
+			}
+			out = append(out, string(b[:i]))
+			b = b[i:]
+			continue
+		}
+		_, size := utf8.DecodeRune(b)
+		out = append(out, string(b[:size]))
+		b = b[size:]
+	}
+	return out
+}
+
+// pickerEnabled reports whether to show the picker; agent is who ran the
+// command (detectAgent), and agents never get it.
+func pickerEnabled(agent string) bool {
+	cfg, err := config.Load()
+	if err != nil || !cfg.Suggest.Picker {
+		return false
+	}
+	return agent == "" && execx.IsTTY() && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// pickAndRun shows the picker for argvSafe's suggestions and runs the chosen
+// command through runShim so it is recorded like any other invocation.
+// handled is false when there was nothing to pick; otherwise code is the exit
+// code to return (failedCode if the user dismissed it).
+func pickAndRun(tool, ctxKey string, argvSafe []string, hints usageHints, failedCode int) (code int, handled bool) {
+	var sugs []Suggestion
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		sugs, err = rankSuggestions(db, tool, ctxKey, argvSafe, hints, suggestionCount())
+		return err
+	}); err != nil || len(sugs) == 0 {
+		return 0, false
+	}
+
+	now := time.Now()
+	items := make([]pickItem, 0, len(sugs))
+	for _, s := range sugs {
+		cmd, why := formatSuggestion(s, hints, now)
+		items = append(items, pickItem{Cmd: cmd, Why: why})
+	}
+
+	restore, err := execx.MakeRaw(os.Stdin)
+	if err != nil {
+		return 0, false
+	}
+	cmd, ok := runPicker(os.Stdin, os.Stderr, items)
+	restore()
+	if !ok {
+		return failedCode, true
+	}
+	if code, ran := runEdited(tool, hints, cmd); ran {
+		return code, true
+	}
+	return failedCode, true
+}
+
+// runEdited runs a command line chosen or edited by the user through the
+// shim (see rerun).
+func runEdited(tool string, hints usageHints, cmd string) (code int, ran bool) {
+	argv, err := execx.ShellSplit(cmd)
+	if err != nil || len(argv) == 0 {
+		fmt.Fprintf(os.Stderr, "ackchyually: can't parse %q: %v\n", cmd, err)
+		return 0, false
+	}
+	if containsRedacted(argv) {
+		fmt.Fprintln(os.Stderr, "ackchyually: replace <redacted> before running")
+		return 0, false
+	}
+	fmt.Fprintln(os.Stderr, "ackchyually: running:")
+	fmt.Fprintln(os.Stderr, "  "+execx.ShellJoin(argv))
+	return rerun(tool, hints.Exe, toolArgv(tool, hints.Run, argv)), true
+}
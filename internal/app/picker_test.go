@@ -0,0 +1,64 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplitKeys(t *testing.T) {
+	got := splitKeys([]byte("\x1b[Bj\x1b\réx\x1bOA"))
+	want := []string{"\x1b[B", "j", "\x1b", "\r", "é", "x", "\x1bOA"}
+	if !slicesEqual(got, want) {
+		t.Fatalf("splitKeys = %q, want %q", got, want)
+	}
+}
+
+func TestPicker_Keys(t *testing.T) {
+	items := []pickItem{{Cmd: "git log --pretty=oneline"}, {Cmd: "git log --oneline"}, {Cmd: "git log -n 5"}}
+
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"enter runs top", "\r", "git log --pretty=oneline", true},
+		{"down then enter", "\x1b[B\r", "git log --oneline", true},
+		{"clamped at bottom", "jjjj\r", "git log -n 5", true},
+		{"up clamps at top", "\x1b[A\x1b[A\r", "git log --pretty=oneline", true},
+		{"esc dismisses", "\x1b", "", false},
+		{"ctrl-c dismisses", "j\x03", "", false},
+		{"edit appends", "je -- a.go\r", "git log --oneline -- a.go", true},
+		{"edit backspace", "e" + strings.Repeat("\x7f", 14) + "short\r", "git log --short", true},
+		{"esc leaves edit mode", "jex\x1b\r", "git log --oneline", true},
+		{"edited to empty dismisses", "e\x15\r", "", false},
+		{"eof dismisses", "j", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, ok := runPicker(strings.NewReader(tt.input), &out, items)
+			if got != tt.want || ok != tt.wantOK {
+				t.Fatalf("runPicker(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.wantOK)
+			}
+			if !strings.Contains(out.String(), "> git log --pretty=oneline") {
+				t.Fatalf("picker did not render items:\n%q", out.String())
+			}
+		})
+	}
+}
+
+func TestPickerEnabled_NeverForAgents(t *testing.T) {
+	setTempHomeAndCWD(t)
+	cfg := t.TempDir() + "/config.toml"
+	writeFile(t, cfg, "[suggest]\npicker = true\n", 0o600)
+	t.Setenv("ACKCHYUALLY_CONFIG", cfg)
+
+	if pickerEnabled(agentUnknown) {
+		t.Fatal("picker must not activate for agent runs")
+	}
+	if pickerEnabled("") {
+		t.Fatal("picker must not activate without a terminal")
+	}
+}
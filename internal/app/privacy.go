@@ -0,0 +1,72 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joelklabo/ackchyually/internal/config"
+)
+
+// optOutMarker opts a directory tree out of recording when present in the
+// current directory or any parent.
+const optOutMarker = ".ackchyually-ignore"
+
+// optOutReason reports why ctxKey/cwd is opted out of recording, if it is.
+func optOutReason(cfg config.Config, ctxKey, cwd string) (string, bool) {
+	if p, ok := findOptOutMarker(cwd); ok {
+		return "marker " + p, true
+	}
+	for _, k := range cfg.Privacy.IgnoreContexts {
+		if strings.TrimSpace(k) == ctxKey {
+			return "config ignore_contexts " + ctxKey, true
+		}
+	}
+	root := contextPath(ctxKey)
+	for _, pat := range cfg.Privacy.IgnorePaths {
+		if strings.TrimSpace(pat) == "" {
+			continue
+		}
+		if config.MatchPath(pat, cwd) || (root != "" && config.MatchPath(pat, root)) {
+			return "config ignore_paths " + pat, true
+		}
+	}
+	return "", false
+}
+
+// detectOptOut checks the current directory against the user config and
+// opt-out markers. Config errors are ignored: opting out must never break a shim.
+func detectOptOut(ctxKey string) (string, bool) {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.Config{}
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = "."
+	}
+	return optOutReason(cfg, ctxKey, cwd)
+}
+
+func findOptOutMarker(start string) (string, bool) {
+	dir := filepath.Clean(start)
+	for {
+		p := filepath.Join(dir, optOutMarker)
+		if _, err := os.Stat(p); err == nil {
+			return p, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+func contextPath(ctxKey string) string {
+	_, path, ok := strings.Cut(ctxKey, ":")
+	if !ok {
+		return ""
+	}
+	return path
+}
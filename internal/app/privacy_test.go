@@ -0,0 +1,108 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/config"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+func TestOptOutReason(t *testing.T) {
+	tmp := t.TempDir()
+	repo := filepath.Join(tmp, "clients", "acme")
+	sub := filepath.Join(repo, "src")
+	mkdirAll(t, sub)
+
+	if _, ok := optOutReason(config.Config{}, "git:"+repo, sub); ok {
+		t.Fatal("optOutReason: unexpected opt-out with empty config and no marker")
+	}
+
+	cfg := config.Config{Privacy: config.Privacy{IgnoreContexts: []string{"git:" + repo}}}
+	if reason, ok := optOutReason(cfg, "git:"+repo, sub); !ok || !strings.Contains(reason, "ignore_contexts") {
+		t.Fatalf("optOutReason(ignore_contexts) = %q, %v", reason, ok)
+	}
+
+	cfg = config.Config{Privacy: config.Privacy{IgnorePaths: []string{filepath.Join(tmp, "clients", "*")}}}
+	if reason, ok := optOutReason(cfg, "git:"+repo, sub); !ok || !strings.Contains(reason, "ignore_paths") {
+		t.Fatalf("optOutReason(ignore_paths) = %q, %v", reason, ok)
+	}
+
+	writeFile(t, filepath.Join(repo, optOutMarker), "", 0o600)
+	if reason, ok := optOutReason(config.Config{}, "git:"+repo, sub); !ok || !strings.Contains(reason, optOutMarker) {
+		t.Fatalf("optOutReason(marker) = %q, %v", reason, ok)
+	}
+}
+
+func TestRunShim_OptedOut_RecordsNothingAndNoSuggestion(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	t.Setenv("ACKCHYUALLY_TEST_FORCE_TTY", "true")
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	writeFile(t, filepath.Join(cwd, optOutMarker), "", 0o600)
+
+	tmp := t.TempDir()
+	writeExec(t, tmp, "script", "#!/bin/sh\necho 'Usage: script'\nexit 1", "@echo Usage: script\r\n@exit /b 1")
+	t.Setenv("PATH", tmp+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	code, out, errOut := captureStdoutStderr(t, func() int {
+		return RunShim("script", []string{"statu"})
+	})
+	if code != 1 {
+		t.Fatalf("RunShim returned %d want 1", code)
+	}
+	if !strings.Contains(out, "Usage: script") {
+		t.Fatalf("stdout missing tool output, got:\n%s", out)
+	}
+	if strings.Contains(errOut, "ackchyually") {
+		t.Fatalf("stderr has ackchyually output in opted-out repo:\n%s", errOut)
+	}
+
+	var invs []store.Invocation
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		invs, err = db.ListInvocations(ctxKey, "", 10)
+		return err
+	}); err != nil {
+		t.Fatalf("ListInvocations: %v", err)
+	}
+	if len(invs) != 0 {
+		t.Fatalf("expected no recorded invocations, got %d", len(invs))
+	}
+}
+
+func TestHistory_ShowsInvocationsAndOptOut(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	seedInvocation(t, ctxKey, "git", []string{"git", "status"}, time.Now(), 0)
+
+	code, out, _ := captureStdoutStderr(t, func() int {
+		return historyCmd(nil)
+	})
+	if code != 0 {
+		t.Fatalf("history returned %d", code)
+	}
+	if !strings.Contains(out, "git status") || !strings.Contains(out, "exit=0") {
+		t.Fatalf("history output missing invocation:\n%s", out)
+	}
+	if strings.Contains(out, "opted out") {
+		t.Fatalf("history unexpectedly reports opt-out:\n%s", out)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	writeFile(t, filepath.Join(cwd, optOutMarker), "", 0o600)
+
+	_, out, _ = captureStdoutStderr(t, func() int {
+		return historyCmd(nil)
+	})
+	if !strings.Contains(out, "opted out") {
+		t.Fatalf("history output missing opt-out notice:\n%s", out)
+	}
+}
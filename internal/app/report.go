@@ -0,0 +1,209 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/execx"
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+// reportVersion is bumped on incompatible changes to failureReport.
+const reportVersion = 1
+
+// failureReport is the machine-readable record of a failed invocation and what
+// ackchyually suggests instead, written as one JSON line (see
+// structuredOutput). It is a stable contract for agents: fields may be added,
+// never renamed or removed within a version.
+type failureReport struct {
+	Version        int                `json:"version"`
+	Tool           string             `json:"tool"`
+	Argv           []string           `json:"argv"`
+	ExitCode       int                `json:"exit_code"`
+	Context        string             `json:"context"`
+	Classification reportClass        `json:"classification"`
+	Suggestions    []reportSuggestion `json:"suggestions"`
+}
+
+type reportClass struct {
+	Class string `json:"class"`
+	Rule  string `json:"rule,omitempty"`
+	Line  string `json:"line,omitempty"`
+}
+
+type reportSuggestion struct {
+	Command string   `json:"command"` // shell-quoted, ready to run
+	Argv    []string `json:"argv"`
+	// Source is "learned" (fixed this exact failure before), "patched" (typed
+	// command with typos fixed from history), "completions" or "help"
+	// (patched from the tool's own vocabulary), "history" (a similar
+	// command that succeeded here) or "login" (after an auth failure).
+	Source     string      `json:"source"`
+	Confidence float64     `json:"confidence"` // 0..1, for ordering and thresholds
+	Reasons    []string    `json:"reasons,omitempty"`
+	Fixes      []reportFix `json:"fixes,omitempty"`
+}
+
+type reportFix struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// structuredOutput returns where the JSON report goes instead of the printed
+// suggestions: $ACKCHYUALLY_SUGGEST_FILE (appended), $ACKCHYUALLY_SUGGEST_FD,
+// or stderr with ACKCHYUALLY_OUTPUT=json. ok is false for the default
+// human-readable output.
+func structuredOutput() (w io.WriteCloser, ok bool) {
+	if path := strings.TrimSpace(os.Getenv("ACKCHYUALLY_SUGGEST_FILE")); path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err == nil {
+			return f, true
+		}
+		fmt.Fprintln(os.Stderr, "ackchyually: ACKCHYUALLY_SUGGEST_FILE:", err)
+	}
+	if s := strings.TrimSpace(os.Getenv("ACKCHYUALLY_SUGGEST_FD")); s != "" {
+		fd, err := strconv.Atoi(s)
+		if err == nil {
+			var fw io.Writer
+			fw, err = openSuggestFD(fd)
+			if err == nil {
+				return nopCloser{fw}, true
+			}
+		}
+		fmt.Fprintln(os.Stderr, "ackchyually: ACKCHYUALLY_SUGGEST_FD:", err)
+	}
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("ACKCHYUALLY_OUTPUT")), "json") {
+		return nopCloser{os.Stderr}, true
+	}
+	return nil, false
+}
+
+// structuredOutputSet reports whether any of structuredOutput's settings is
+// present, without opening anything.
+func structuredOutputSet() bool {
+	return strings.TrimSpace(os.Getenv("ACKCHYUALLY_SUGGEST_FILE")) != "" ||
+		strings.TrimSpace(os.Getenv("ACKCHYUALLY_SUGGEST_FD")) != "" ||
+		strings.EqualFold(strings.TrimSpace(os.Getenv("ACKCHYUALLY_OUTPUT")), "json")
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// buildReport gathers the suggestions for a failed invocation the same way the
+// printed output does.
+func buildReport(p profile.Profile, tool, ctxKey string, argvSafe []string, exitCode int, cls profile.Match, hints usageHints) failureReport {
+	rep := failureReport{
+		Version:        reportVersion,
+		Tool:           tool,
+		Argv:           argvSafe,
+		ExitCode:       exitCode,
+		Context:        ctxKey,
+		Classification: reportClass{Class: cls.Class, Rule: cls.Rule, Line: cls.Line},
+		Suggestions:    []reportSuggestion{},
+	}
+	if err := store.WithDB(func(db *store.DB) error {
+		switch cls.Class {
+		case profile.ClassUsage:
+			sugs, err := rankSuggestions(db, tool, ctxKey, argvSafe, hints, suggestionCount())
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			for _, s := range sugs {
+				rep.Suggestions = append(rep.Suggestions, toReportSuggestion(s, hints, now))
+			}
+		case profile.ClassAuth:
+			cands, err := db.ListSuccessCandidates(tool, ctxKey, 200)
+			if err != nil {
+				return err
+			}
+			if argv := pickLogin(cands, p.LoginTokens); len(argv) > 0 {
+				argv = hints.command(argv)
+				rep.Suggestions = append(rep.Suggestions, reportSuggestion{
+					Command:    execx.ShellJoin(argv),
+					Argv:       argv,
+					Source:     "login",
+					Confidence: 0.5,
+					Reasons:    []string{"last successful login in this repo"},
+				})
+			}
+		}
+		return nil
+	}); err != nil {
+		_ = err // best-effort
+	}
+	return rep
+}
+
+func toReportSuggestion(s Suggestion, hints usageHints, now time.Time) reportSuggestion {
+	cmd, _ := formatSuggestion(s, hints, now)
+	r := reportSuggestion{
+		Command:    cmd,
+		Argv:       hints.command(s.Argv),
+		Source:     suggestionSource(s),
+		Confidence: confidence(s),
+		Reasons:    s.Reasons(now),
+	}
+	for _, f := range s.Fixes {
+		r.Fixes = append(r.Fixes, reportFix(f))
+	}
+	return r
+}
+
+func suggestionSource(s Suggestion) string {
+	switch {
+	case s.Learned > 0:
+		return "learned"
+	case s.Patched && s.Source != "":
+		return s.Source
+	case s.Patched:
+		return "patched"
+	default:
+		return "history"
+	}
+}
+
+// confidence maps the ranking signals onto 0..1: a correction that worked
+// before is near-certain, a patch keeps everything that was right, and a
+// similar past command is only as good as its overlap with what was typed.
+func confidence(s Suggestion) float64 {
+	var c float64
+	switch {
+	case s.Learned > 0:
+		c = 0.8 + 0.05*float64(s.Learned)
+	case s.Patched && s.Source == vocabCompletions:
+		c = 0.8
+	case s.Patched && s.Source != "":
+		c = 0.7
+	case s.Patched:
+		c = 0.75 + 0.01*float64(s.Uses)
+	default:
+		c = 0.2 + 0.1*float64(s.Match)
+		if len(s.Fixes) > 0 {
+			c += 0.1
+		}
+		if s.SameSubcommand {
+			c += 0.05
+		}
+		c = math.Min(c, 0.65)
+	}
+	return math.Round(math.Min(c, 0.95)*100) / 100
+}
+
+func writeReport(w io.Writer, rep failureReport) {
+	b, err := json.Marshal(rep)
+	if err != nil {
+		return
+	}
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		_ = err // best-effort
+	}
+}
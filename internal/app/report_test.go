@@ -0,0 +1,166 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupUsageTool installs a "script" tool that always fails with a usage
+// error and seeds one success for it.
+func setupUsageTool(t *testing.T) {
+	t.Helper()
+	ctxKey := setTempHomeAndCWD(t)
+	tmp := t.TempDir()
+	writeExec(t, tmp, "script", "#!/bin/sh\necho 'Usage: script status'\nexit 1", "@echo Usage: script status\r\n@exit /b 1")
+	t.Setenv("PATH", tmp+string(os.PathListSeparator)+os.Getenv("PATH"))
+	seedInvocation(t, ctxKey, "script", []string{"script", "status", "--short"}, time.Now(), 0)
+}
+
+func decodeReport(t *testing.T, line string) failureReport {
+	t.Helper()
+	var rep failureReport
+	if err := json.Unmarshal([]byte(strings.TrimSpace(line)), &rep); err != nil {
+		t.Fatalf("decode report %q: %v", line, err)
+	}
+	return rep
+}
+
+func TestRunShim_OutputJSON(t *testing.T) {
+	setupUsageTool(t)
+	t.Setenv("ACKCHYUALLY_OUTPUT", "json")
+
+	code, out, errOut := captureStdoutStderr(t, func() int {
+		return RunShim("script", []string{"statsu", "--short"})
+	})
+	if code != 1 {
+		t.Fatalf("RunShim = %d, want 1", code)
+	}
+	if !strings.Contains(out, "Usage: script status") {
+		t.Fatalf("tool output missing: %q", out)
+	}
+	if strings.Contains(errOut, "ackchyually: suggestion") {
+		t.Fatalf("text suggestion printed in json mode:\n%s", errOut)
+	}
+
+	var line string
+	for _, l := range strings.Split(errOut, "\n") {
+		if strings.HasPrefix(l, "{") {
+			line = l
+		}
+	}
+	rep := decodeReport(t, line)
+	if rep.Version != reportVersion || rep.Tool != "script" || strings.Join(rep.Argv, " ") != "script statsu --short" || rep.ExitCode != 1 {
+		t.Fatalf("report header = %+v", rep)
+	}
+	if rep.Classification.Class != "usage" || rep.Classification.Rule == "" {
+		t.Fatalf("classification = %+v", rep.Classification)
+	}
+	if len(rep.Suggestions) != 1 {
+		t.Fatalf("suggestions = %+v", rep.Suggestions)
+	}
+	s := rep.Suggestions[0]
+	if s.Command != "script status --short" || s.Source != "patched" || s.Confidence <= 0.5 || len(s.Fixes) != 1 || s.Fixes[0].To != "status" {
+		t.Fatalf("suggestion = %+v", s)
+	}
+}
+
+func TestRunShim_SuggestFile(t *testing.T) {
+	setupUsageTool(t)
+	path := filepath.Join(t.TempDir(), "suggest.jsonl")
+	t.Setenv("ACKCHYUALLY_SUGGEST_FILE", path)
+
+	for i := 0; i < 2; i++ {
+		_, _, errOut := captureStdoutStderr(t, func() int {
+			return RunShim("script", []string{"statsu"})
+		})
+		if strings.Contains(errOut, "ackchyually") || strings.Contains(errOut, "{") {
+			t.Fatalf("stderr should only carry the tool's output:\n%s", errOut)
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read suggest file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want one report per failure, got %q", b)
+	}
+	if rep := decodeReport(t, lines[1]); len(rep.Suggestions) == 0 || rep.Suggestions[0].Command != "script status" {
+		t.Fatalf("report = %+v", rep)
+	}
+}
+
+func TestRunShim_SuggestFD(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file descriptors are unix-only")
+	}
+	setupUsageTool(t)
+	f, err := os.Create(filepath.Join(t.TempDir(), "fd.out"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer f.Close()
+	t.Setenv("ACKCHYUALLY_SUGGEST_FD", strconv.Itoa(int(f.Fd())))
+
+	_, _, errOut := captureStdoutStderr(t, func() int {
+		return RunShim("script", []string{"statsu"})
+	})
+	if strings.Contains(errOut, "ackchyually") {
+		t.Fatalf("stderr = %q", errOut)
+	}
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if rep := decodeReport(t, string(b)); rep.Classification.Class != "usage" {
+		t.Fatalf("report = %+v", rep)
+	}
+
+	// A closed fd falls back to the printed suggestion with a warning.
+	t.Setenv("ACKCHYUALLY_SUGGEST_FD", "987")
+	_, _, errOut = captureStdoutStderr(t, func() int {
+		return RunShim("script", []string{"statsu"})
+	})
+	if !strings.Contains(errOut, "ACKCHYUALLY_SUGGEST_FD") || !strings.Contains(errOut, "ackchyually: suggestion") {
+		t.Fatalf("stderr = %q", errOut)
+	}
+}
+
+func TestConfidence_FollowsRanking(t *testing.T) {
+	learned := confidence(Suggestion{Learned: 2})
+	patched := confidence(Suggestion{Patched: true, Uses: 1})
+	help := confidence(Suggestion{Patched: true, Source: vocabHelp})
+	weak := confidence(Suggestion{Match: 1})
+	if !(learned > patched && patched > help && help > weak) {
+		t.Fatalf("confidence not ordered: learned=%v patched=%v help=%v weak=%v", learned, patched, help, weak)
+	}
+	if c := confidence(Suggestion{Learned: 50}); c > 0.95 {
+		t.Fatalf("confidence = %v, want capped at 0.95", c)
+	}
+}
+
+func TestStructuredOutputSet(t *testing.T) {
+	for _, env := range []string{"ACKCHYUALLY_SUGGEST_FILE", "ACKCHYUALLY_SUGGEST_FD", "ACKCHYUALLY_OUTPUT"} {
+		t.Setenv(env, "")
+	}
+	if structuredOutputSet() {
+		t.Fatal("structuredOutputSet() = true with nothing set")
+	}
+	t.Setenv("ACKCHYUALLY_OUTPUT", "text")
+	if structuredOutputSet() {
+		t.Fatal("structuredOutputSet() = true for ACKCHYUALLY_OUTPUT=text")
+	}
+	// A file that can't be opened still means the caller wants no extra
+	// output on stderr: the agent CLI tip stays off.
+	t.Setenv("ACKCHYUALLY_SUGGEST_FILE", filepath.Join(t.TempDir(), "missing", "x.jsonl"))
+	if !structuredOutputSet() {
+		t.Fatal("structuredOutputSet() = false with ACKCHYUALLY_SUGGEST_FILE set")
+	}
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/joelklabo/ackchyually/internal/execx"
+)
+
+const runUsage = "usage: ackchyually run [--as <name>] -- <command...>"
+
+// runCmd implements `ackchyually run`: what a shim does, for a tool with no
+// shim installed or one called by path. The command is recorded as the tool
+// itself (not as an ackchyually command), under --as or its executable's name.
+func runCmd(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	as := fs.String("as", "", "record the command under this tool name (default: the executable's name)")
+	// Flags end at "--" or at the command: the rest belongs to the tool.
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	argv := fs.Args()
+	if len(argv) == 0 || argv[0] == "" {
+		fmt.Fprintln(os.Stderr, runUsage)
+		return 2
+	}
+
+	exe, err := resolveRunExe(argv[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ackchyually:", err)
+		return 127
+	}
+	tool := strings.TrimSpace(*as)
+	if tool == "" {
+		tool = strings.TrimSuffix(filepath.Base(argv[0]), ".exe")
+	}
+	return runShimExe(tool, exe, argv[1:], true)
+}
+
+// resolveRunExe finds the binary `ackchyually run` runs: a path as given, a
+// name on PATH. Shims are skipped either way, so the run isn't recorded twice.
+func resolveRunExe(name string) (string, error) {
+	if !strings.ContainsRune(name, '/') && !strings.ContainsRune(name, filepath.Separator) {
+		return execx.WhichSkippingShims(name)
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	if filepath.Dir(abs) == filepath.Clean(execx.ShimDir()) {
+		return execx.WhichSkippingShims(filepath.Base(abs))
+	}
+	exe, err := exec.LookPath(abs)
+	if err != nil {
+		var ee *exec.Error
+		if errors.As(err, &ee) {
+			err = ee.Err
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return exe, nil
+}
+
+// runPrefix is how a printed command has to start to run exe as tool: nil
+// when the tool's name finds exe on PATH, else `ackchyually run [--as tool]
+// -- exe` (a binary run by path, or recorded under another name).
+func runPrefix(tool, exe string) []string {
+	if exe == "" {
+		return nil
+	}
+	if found, err := execx.WhichSkippingShims(tool); err == nil && filepath.Clean(found) == filepath.Clean(exe) {
+		return nil
+	}
+	prefix := []string{"ackchyually", "run"}
+	if tool != strings.TrimSuffix(filepath.Base(exe), ".exe") {
+		prefix = append(prefix, "--as", tool)
+	}
+	return append(prefix, "--", exe)
+}
+
+// commandLine is a tool's argv with its name replaced by prefix (runPrefix).
+func commandLine(prefix, argv []string) []string {
+	if len(prefix) == 0 || len(argv) == 0 {
+		return argv
+	}
+	return append(append([]string{}, prefix...), argv[1:]...)
+}
+
+// toolArgv undoes commandLine for a command line the user picked or edited.
+// Anything else is returned as is.
+func toolArgv(tool string, prefix, argv []string) []string {
+	if len(prefix) == 0 || len(argv) < len(prefix) || !slicesEqual(argv[:len(prefix)], prefix) {
+		return argv
+	}
+	return append([]string{tool}, argv[len(prefix):]...)
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+func TestRunCmd_WrapsToolByPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script tool")
+	}
+	ctxKey := setTempHomeAndCWD(t)
+	t.Setenv("ACKCHYUALLY_TEST_FORCE_TTY", "true")
+
+	// Not on PATH: only reachable by path.
+	dir := filepath.Join(t.TempDir(), "opt", "foo", "bin")
+	mkdirAll(t, dir)
+	writeExec(t, dir, "foo", "#!/bin/sh\n[ \"$1\" = status ] && { echo ok; exit 0; }\necho \"unknown command: $1\" >&2\nexit 2\n", "")
+	exe := filepath.Join(dir, "foo")
+
+	seedInvocation(t, ctxKey, "foo", []string{"foo", "status"}, time.Now().Add(-time.Minute), 0)
+
+	code, _, errOut := captureStdoutStderr(t, func() int {
+		return RunCLI([]string{"run", "--", exe, "statu"})
+	})
+	if code != 2 {
+		t.Fatalf("run exit=%d, want the tool's 2; stderr=%q", code, errOut)
+	}
+	// foo isn't on PATH: the suggestion says how to run it.
+	if !strings.Contains(errOut, "unknown command: statu") || !strings.Contains(errOut, "  ackchyually run -- "+exe+" status\n") {
+		t.Fatalf("expected the tool's error and a runnable suggestion, got:\n%s", errOut)
+	}
+
+	t.Setenv("ACKCHYUALLY_OUTPUT", "json")
+	_, _, errOut = captureStdoutStderr(t, func() int {
+		return RunCLI([]string{"run", "--as", "foo", "--", exe, "statu"})
+	})
+	var rep failureReport
+	if err := json.Unmarshal([]byte(errOut[strings.Index(errOut, "{"):]), &rep); err != nil {
+		t.Fatalf("report: %v\n%s", err, errOut)
+	}
+	if len(rep.Suggestions) == 0 || rep.Suggestions[0].Command != "ackchyually run -- "+exe+" status" ||
+		strings.Join(rep.Suggestions[0].Argv, " ") != "ackchyually run -- "+exe+" status" {
+		t.Fatalf("report suggestions = %+v", rep.Suggestions)
+	}
+	t.Setenv("ACKCHYUALLY_OUTPUT", "")
+
+	code, out, _ := captureStdoutStderr(t, func() int {
+		return RunCLI([]string{"run", "--as", "bar", exe, "status"})
+	})
+	if code != 0 || out != "ok\n" {
+		t.Fatalf("run --as exit=%d stdout=%q", code, out)
+	}
+
+	var invs []store.Invocation
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		invs, err = db.ListInvocations(ctxKey, "", 10)
+		return err
+	}); err != nil {
+		t.Fatalf("ListInvocations: %v", err)
+	}
+	var got []string
+	for _, inv := range invs {
+		got = append(got, inv.Tool+" "+inv.ExePath+" "+inv.ArgvJSON)
+	}
+	// Recorded as the tools themselves, not as ackchyually commands.
+	want := []string{
+		"bar " + exe + ` ["bar","status"]`,
+		"foo " + exe + ` ["foo","statu"]`,
+		"foo " + exe + ` ["foo","statu"]`,
+		`foo /bin/foo ["foo","status"]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("recorded:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRunCmd_AutoExecRerunsSameBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script tool")
+	}
+	ctxKey := setTempHomeAndCWD(t)
+	dir := t.TempDir()
+	writeExec(t, dir, "foo", "#!/bin/sh\necho \"ran $*\"\n", "")
+	seedInvocation(t, ctxKey, "foo", []string{"foo", "status"}, time.Now(), 0)
+
+	code, out, _ := captureStdoutStderr(t, func() int {
+		c, ok := autoExecKnownSuccess("foo", filepath.Join(dir, "foo"), ctxKey, []string{"foo", "statu"})
+		if !ok {
+			return -1
+		}
+		return c
+	})
+	if code != 0 || out != "ran status\n" {
+		t.Fatalf("auto-exec exit=%d stdout=%q; want the same binary re-run off PATH", code, out)
+	}
+}
+
+func TestRunCmd_Errors(t *testing.T) {
+	setTempHomeAndCWD(t)
+
+	if code, _, errOut := captureStdoutStderr(t, func() int { return RunCLI([]string{"run", "--"}) }); code != 2 || !strings.Contains(errOut, "usage: ackchyually run") {
+		t.Fatalf("run without a command: exit=%d stderr=%q", code, errOut)
+	}
+	missing := filepath.Join(t.TempDir(), "nope")
+	if code, _, errOut := captureStdoutStderr(t, func() int { return RunCLI([]string{"run", missing}) }); code != 127 || !strings.Contains(errOut, missing) {
+		t.Fatalf("run of a missing binary: exit=%d stderr=%q", code, errOut)
+	}
+	t.Setenv("PATH", t.TempDir())
+	if code, _, _ := captureStdoutStderr(t, func() int { return RunCLI([]string{"run", "nope-not-a-tool"}) }); code != 127 {
+		t.Fatalf("run of an unknown name: exit=%d, want 127", code)
+	}
+}
+
+func TestRunPrefix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script tool")
+	}
+	setTempHomeAndCWD(t)
+	dir := t.TempDir()
+	writeExec(t, dir, "foo", "#!/bin/sh\n", "")
+	t.Setenv("PATH", dir)
+	onPath := filepath.Join(dir, "foo")
+	elsewhere := filepath.Join(t.TempDir(), "foo")
+
+	tests := []struct {
+		tool, exe string
+		want      string
+	}{
+		{"foo", onPath, ""},
+		{"foo", elsewhere, "ackchyually run -- " + elsewhere},
+		{"bar", onPath, "ackchyually run --as bar -- " + onPath},
+		{"foo", "", ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(runPrefix(tt.tool, tt.exe), " "); got != tt.want {
+			t.Errorf("runPrefix(%q, %q) = %q, want %q", tt.tool, tt.exe, got, tt.want)
+		}
+	}
+
+	prefix := runPrefix("bar", onPath)
+	line := commandLine(prefix, []string{"bar", "status", "-v"})
+	if got := toolArgv("bar", prefix, line); strings.Join(got, " ") != "bar status -v" {
+		t.Errorf("toolArgv(commandLine(...)) = %q", got)
+	}
+	if got := toolArgv("bar", prefix, []string{"bar", "log"}); strings.Join(got, " ") != "bar log" {
+		t.Errorf("toolArgv of a plain command = %q", got)
+	}
+}
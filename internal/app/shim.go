@@ -12,9 +12,11 @@ import (
 
 	"github.com/joelklabo/ackchyually/internal/contextkey"
 	"github.com/joelklabo/ackchyually/internal/execx"
+	"github.com/joelklabo/ackchyually/internal/profile"
 	"github.com/joelklabo/ackchyually/internal/redact"
 	"github.com/joelklabo/ackchyually/internal/store"
 	"github.com/joelklabo/ackchyually/internal/toolid"
+	"github.com/joelklabo/ackchyually/internal/ui"
 )
 
 func RunShim(tool string, args []string) int {
@@ -27,15 +29,47 @@ func runShim(tool string, args []string, allowAutoExec bool) int {
 		fmt.Fprintln(os.Stderr, "ackchyually:", err)
 		return 127
 	}
+	return runShimExe(tool, exe, args, allowAutoExec)
+}
+
+// runShimExe is runShim for a binary already resolved: by the shim's PATH
+// lookup, or given to `ackchyually run` by path.
+func runShimExe(tool, exe string, args []string, allowAutoExec bool) int {
+	if passthrough(tool) {
+		return execPassthrough(tool, exe, args)
+	}
 
 	ctxKey := contextkey.Detect()
-	ti, err := toolid.Identify(exe)
-	if err != nil {
-		ti = toolid.ToolIdentity{}
+	if _, optedOut := detectOptOut(ctxKey); optedOut {
+		return runOptedOut(exe, args)
+	}
+
+	// A binary not seen before is identified after the fact, in the
+	// background; its invocations get their tool_id then.
+	ti, known := toolid.Lookup(exe)
+	if !known && identifyInline() {
+		if id, err := toolid.Identify(exe); err == nil {
+			ti, known = id, true
+		}
+	}
+	// Stamped before the run: the identifier only credits this invocation to
+	// the binary if it is still this one.
+	var stamp store.FileStamp
+	queue := false
+	if !known {
+		if st, err := toolid.Stamp(exe); err == nil {
+			stamp, queue = st, true
+		}
+	}
+	agent := detectAgent()
+	parentID := parentRunID()
+	var runID string
+	if lineageEnabled() {
+		runID = newRunID()
 	}
 
 	start := time.Now()
-	res, err := execx.Run(exe, args)
+	res, err := runTool(exe, args, runID)
 	if err != nil {
 		var ee *exec.ExitError
 		if !errors.As(err, &ee) {
@@ -44,7 +78,9 @@ func runShim(tool string, args []string, allowAutoExec bool) int {
 	}
 	dur := time.Since(start)
 
-	usageish := isUsageish(args, res.ExitCode, res)
+	p := profile.Load(tool)
+	cls := classifyInvocation(p, args, res)
+	usageish := cls.Class == profile.ClassUsage
 	exitForLog := res.ExitCode
 	if usageish && res.ExitCode == 0 {
 		exitForLog = 64
@@ -58,8 +94,14 @@ func runShim(tool string, args []string, allowAutoExec bool) int {
 	combinedTailSafe := r.RedactText(res.CombinedTail)
 
 	// best-effort logging
+	startIdentify := false
 	if err := store.WithDB(func(db *store.DB) error {
-		return db.InsertInvocation(store.Invocation{
+		if cls.Class == profile.ClassOK && parentID == "" {
+			if err := learnCorrection(db, ctxKey, tool, argvSafe, start); err != nil {
+				_ = err // best-effort
+			}
+		}
+		id, err := db.InsertInvocationID(store.Invocation{
 			At:           start,
 			DurationMS:   dur.Milliseconds(),
 			ContextKey:   ctxKey,
@@ -72,306 +114,196 @@ func runShim(tool string, args []string, allowAutoExec bool) int {
 			StdoutTail:   stdoutTailSafe,
 			StderrTail:   stderrTailSafe,
 			CombinedTail: combinedTailSafe,
+			Class:        cls.Class,
+			Agent:        agentLabel(agent),
+			Signal:       res.Signal,
+			CPUUserMS:    res.UserCPU.Milliseconds(),
+			CPUSysMS:     res.SysCPU.Milliseconds(),
+			MaxRSSKB:     res.MaxRSS / 1024,
+			StdoutBytes:  res.StdoutBytes,
+			StderrBytes:  res.StderrBytes,
+			RunID:        runID,
+			ParentRunID:  parentID,
 		})
+		if err != nil {
+			return err
+		}
+		if queue {
+			if startIdentify, err = db.QueueToolIdentify(exe, stamp, id, time.Now()); err != nil {
+				return err
+			}
+		}
+		if cls.Class == profile.ClassUsage && parentID == "" && !res.Interrupted {
+			// Suggestions only read the tool's cached --help and completions;
+			// a miss is filled in the background for the next failure.
+			probe, err := queueVocabProbe(db, exe, ti.SHA256, argvSafe)
+			if err != nil {
+				return err
+			}
+			startIdentify = startIdentify || probe
+		}
+		return nil
 	}); err != nil {
 		_ = err // best-effort
 	}
-
-	if usageish {
-		if allowAutoExec && autoExecKnownSuccessEnabled() && execx.IsTTY() {
-			if code, ok := autoExecKnownSuccess(tool, ctxKey, argvSafe); ok {
-				return code
-			}
-		}
-		suggestKnownGood(tool, ctxKey, argvSafe)
+	if startIdentify {
+		identifyInBackground()
 	}
 
-	maybePrintAgentCLIHint(time.Now())
-	return res.ExitCode
-}
-
-func isUsageish(args []string, code int, res execx.Result) bool {
-	if code == 0 {
-		// Some tools print usage/errors but still exit 0. Don't treat explicit help
-		// invocations as failures. Be conservative here because many tools can emit
-		// structured output (e.g., JSON) that may contain strings like "Usage:".
-		if isHelpInvocationArgs(args) {
-			return false
-		}
-		return looksUsageishOnSuccess(res)
+	if res.Interrupted {
+		// Whoever stopped the run wants it over with: no suggestions or tips.
+		return res.ExitCode
 	}
-	if code == 64 {
-		return true
+	if cls.Class == profile.ClassTUI {
+		// The tool ran; there's nothing to correct.
+		return res.ExitCode
 	}
-	t := res.StdoutTail + res.StderrTail + res.CombinedTail
-	return looksUsageish(t)
-}
-
-func looksUsageishOnSuccess(res execx.Result) bool {
-	var t string
-	switch res.Mode {
-	case "pipes":
-		t = res.StderrTail
-	case "pty":
-		t = res.CombinedTail
-	default:
-		t = res.StderrTail
-		if strings.TrimSpace(t) == "" {
-			t = res.CombinedTail
-		}
+	if parentID != "" {
+		// A call made by another tool: whoever ran that one gets the
+		// suggestions, not the tool in between.
+		return res.ExitCode
 	}
-	return looksUsageishLineStartOnSuccess(t)
-}
 
-func looksUsageishLineStartOnSuccess(t string) bool {
-	for _, line := range strings.Split(t, "\n") {
-		l := strings.TrimSpace(stripANSI(line))
-		if l == "" {
-			continue
+	hints := usageHints{Line: cls.Line, Exe: exe, ToolSHA: ti.SHA256, Agent: agent}
+	if cls.Class != profile.ClassOK {
+		hints.Run = runPrefix(tool, exe)
+		// Structured output replaces the printed suggestions and prompts.
+		if w, ok := structuredOutput(); ok {
+			writeReport(w, buildReport(p, tool, ctxKey, argvSafe, res.ExitCode, cls, hints))
+			_ = w.Close()
+			return res.ExitCode
 		}
+	}
 
-		// Avoid treating structured data (common for successful exit=0) as an error.
-		if strings.HasPrefix(l, "{") || strings.HasPrefix(l, "[") {
-			continue
-		}
-		if strings.HasPrefix(l, "\"") && strings.Contains(l, "\":") {
-			continue
+	switch cls.Class {
+	case profile.ClassUsage:
+		if allowAutoExec && autoExecKnownSuccessEnabled() && execx.IsTTY() {
+			if code, ok := autoExecKnownSuccess(tool, exe, ctxKey, argvSafe); ok {
+				return code
+			}
 		}
-
-		if looksUsageishPrefixOnSuccess(l) {
-			return true
+		if allowAutoExec && confirmEnabled(agent) {
+			if code, handled := confirmAndRun(tool, ctxKey, argvSafe, hints, res.ExitCode); handled {
+				return code
+			}
 		}
-		if rest, ok := stripToolPrefix(l); ok && looksUsageishPrefixOnSuccess(rest) {
-			return true
+		if allowAutoExec && pickerEnabled(agent) {
+			if code, handled := pickAndRun(tool, ctxKey, argvSafe, hints, res.ExitCode); handled {
+				return code
+			}
 		}
+		suggestKnownGood(tool, ctxKey, argvSafe, hints)
+	case profile.ClassAuth:
+		suggestLastLogin(p, tool, ctxKey, hints)
 	}
-	return false
+
+	maybePrintAgentCLIHint(time.Now(), agent)
+	return res.ExitCode
 }
 
-func looksUsageishPrefixOnSuccess(line string) bool {
-	l := strings.ToLower(strings.TrimSpace(line))
-	switch {
-	case (strings.HasPrefix(l, "usage:") || strings.HasPrefix(l, "usage of")) && lineLooksLikeUsageBanner(l),
-		strings.HasPrefix(l, "flag provided but not defined"),
-		strings.HasPrefix(l, "unknown flag"),
-		strings.HasPrefix(l, "unknown shorthand flag"),
-		strings.HasPrefix(l, "unknown command"),
-		strings.HasPrefix(l, "unknown subcommand"),
-		strings.HasPrefix(l, "unknown option"),
-		strings.HasPrefix(l, "unknown --write-out variable"),
-		strings.HasPrefix(l, "unrecognized option"),
-		strings.HasPrefix(l, "unrecognized argument"),
-		strings.HasPrefix(l, "invalid option"),
-		strings.HasPrefix(l, "invalid --"),
-		strings.HasPrefix(l, "url rejected"),
-		strings.HasPrefix(l, "invalid regexp"),
-		strings.HasPrefix(l, "requires a value"),
-		strings.HasPrefix(l, "requires an argument"),
-		strings.HasPrefix(l, "requires parameter"),
-		strings.HasPrefix(l, "requires at least"),
-		strings.HasPrefix(l, "requires exactly"),
-		strings.HasPrefix(l, "wrong number of arguments"),
-		strings.HasPrefix(l, "missing required"),
-		strings.HasPrefix(l, "must be absolute path"),
-		strings.HasPrefix(l, "only one reference expected"),
-		strings.HasPrefix(l, "needed a single revision"),
-		strings.HasPrefix(l, "missing colon separator"),
-		(strings.HasPrefix(l, "error:") && lineLooksLikeCLIError(l)),
-		(strings.HasPrefix(l, "fatal:") && lineLooksLikeCLIError(l)),
-		(strings.HasPrefix(l, "try '") && strings.Contains(l, "--help")):
-		return true
+// rerun runs a suggested or edited command line through the shim. The same
+// tool re-runs the same binary, which need not be on PATH (`ackchyually run
+// /opt/foo/bin/foo`); any other tool is looked up by name.
+func rerun(tool, exe string, argv []string) int {
+	if exe != "" && argv[0] == tool {
+		return runShimExe(tool, exe, argv[1:], false)
 	}
-	return false
+	return runShim(argv[0], argv[1:], false)
 }
 
-func stripToolPrefix(line string) (string, bool) {
-	i := strings.IndexByte(line, ':')
-	if i <= 0 {
-		return "", false
-	}
-	prefix := line[:i]
-	if strings.Contains(prefix, " ") {
-		return "", false
-	}
-	for _, r := range prefix {
-		if (r >= 'a' && r <= 'z') ||
-			(r >= 'A' && r <= 'Z') ||
-			(r >= '0' && r <= '9') ||
-			r == '_' || r == '-' || r == '.' || r == '/' {
-			continue
-		}
-		return "", false
-	}
-	rest := strings.TrimSpace(line[i+1:])
-	if rest == "" {
-		return "", false
-	}
-	return rest, true
-}
-
-func lineLooksLikeUsageBanner(line string) bool {
-	// Avoid treating structured/text output that happens to start with "usage:" as a CLI
-	// invocation error. For a successful exit, require hints of actual usage syntax.
-	return strings.Contains(line, " --") ||
-		strings.ContainsAny(line, "[]<>") ||
-		strings.Contains(line, " -")
-}
-
-func lineLooksLikeCLIError(line string) bool {
-	// Avoid treating ordinary output ("ERROR: ...") as a CLI invocation error.
-	for _, s := range []string{
-		"unknown",
-		"invalid",
-		"requires",
-		"expected",
-		"flag",
-		"option",
-		"argument",
-		"command",
-		"subcommand",
-		"for usage",
-		"--help",
-		"-h",
-	} {
-		if strings.Contains(line, s) {
-			return true
+// runOptedOut runs the real tool without touching the DB or printing
+// anything of our own.
+func runOptedOut(exe string, args []string) int {
+	res, err := execx.Run(exe, args)
+	if err != nil {
+		var ee *exec.ExitError
+		if !errors.As(err, &ee) {
+			fmt.Fprintln(os.Stderr, "ackchyually:", err)
 		}
 	}
-	return false
+	return res.ExitCode
 }
 
-func stripANSI(s string) string {
-	if s == "" {
-		return s
+// classifyInvocation assigns a failure class (profile.Classes) to a finished
+// invocation. Usage errors win over every other class.
+func classifyInvocation(p profile.Profile, args []string, res execx.Result) profile.Match {
+	// A run cut short by Ctrl-C or a timeout says nothing about whether the
+	// command was right, whatever the tool printed or returned.
+	if res.Interrupted {
+		return profile.Match{Class: profile.ClassInterrupted, Rule: "signal", Line: res.Signal}
 	}
-	out := make([]byte, 0, len(s))
-	for i := 0; i < len(s); i++ {
-		if s[i] != 0x1b {
-			out = append(out, s[i])
-			continue
-		}
-		// CSI escape sequences (e.g. "\x1b[31m", "\x1b[1;4m").
-		if i+1 < len(s) && s[i+1] == '[' {
-			i += 2
-			for i < len(s) {
-				c := s[i]
-				if (c >= '0' && c <= '9') || c == ';' {
-					i++
-					continue
-				}
-				break
-			}
-			continue
-		}
+	// Editors, pagers and other full-screen tools redraw the whole terminal;
+	// what's left on the screen isn't the tool reporting on the command.
+	if res.FullScreen {
+		return profile.Match{Class: profile.ClassTUI, Rule: "alternate-screen"}
 	}
-	return string(out)
-}
-
-func looksUsageish(t string) bool { //nolint:gocyclo
-	return execx.ContainsFold(t, "usage:") ||
-		execx.ContainsFold(t, "usage of") ||
-		execx.ContainsFold(t, "flag provided but not defined") ||
-		execx.ContainsFold(t, "unknown flag") ||
-		execx.ContainsFold(t, "unknown shorthand flag") ||
-		execx.ContainsFold(t, "unknown command") ||
-		execx.ContainsFold(t, "unknown subcommand") ||
-		execx.ContainsFold(t, "unexpected argument") ||
-		execx.ContainsFold(t, "for usage") ||
-		(execx.ContainsFold(t, "try '") && execx.ContainsFold(t, "--help")) ||
-		(execx.ContainsFold(t, "is not a") && execx.ContainsFold(t, "command") && execx.ContainsFold(t, "--help")) ||
-		(execx.ContainsFold(t, "option") && execx.ContainsFold(t, "is unknown")) ||
-		execx.ContainsFold(t, "requires a value") ||
-		execx.ContainsFold(t, "requires an argument") ||
-		execx.ContainsFold(t, "requires parameter") ||
-		execx.ContainsFold(t, "requires at least") ||
-		execx.ContainsFold(t, "requires exactly") ||
-		(execx.ContainsFold(t, "accepts") && execx.ContainsFold(t, "arg(s)") && execx.ContainsFold(t, "received")) ||
-		execx.ContainsFold(t, "unknown option") ||
-		execx.ContainsFold(t, "unrecognized option") ||
-		execx.ContainsFold(t, "unrecognized argument") ||
-		(execx.ContainsFold(t, "unrecognized --") && execx.ContainsFold(t, " argument")) ||
-		execx.ContainsFold(t, "invalid option") ||
-		(execx.ContainsFold(t, "invalid --") && execx.ContainsFold(t, " option")) ||
-		(execx.ContainsFold(t, "invalid --") && execx.ContainsFold(t, " value")) ||
-		(execx.ContainsFold(t, "invalid --") && execx.ContainsFold(t, " format")) ||
-		execx.ContainsFold(t, "unknown --write-out variable") ||
-		execx.ContainsFold(t, "url rejected") ||
-		execx.ContainsFold(t, "invalid regexp") ||
-		execx.ContainsFold(t, "not an integer") ||
-		execx.ContainsFold(t, "key=value") ||
-		execx.ContainsFold(t, "unknown go command variable") ||
-		execx.ContainsFold(t, "must be absolute path") ||
-		execx.ContainsFold(t, "ambiguous argument") ||
-		(execx.ContainsFold(t, "pathspec") && execx.ContainsFold(t, "did not match")) ||
-		execx.ContainsFold(t, "wrong number of arguments") ||
-		execx.ContainsFold(t, "name required") ||
-		execx.ContainsFold(t, "unknown revision") ||
-		execx.ContainsFold(t, "unknown date format") ||
-		(execx.ContainsFold(t, "option") && execx.ContainsFold(t, "expects")) ||
-		(execx.ContainsFold(t, "option") && execx.ContainsFold(t, "accepts")) ||
-		execx.ContainsFold(t, "valid values") ||
-		execx.ContainsFold(t, "could not find key") ||
-		execx.ContainsFold(t, "unknown change class") ||
-		(execx.ContainsFold(t, "cannot do") && execx.ContainsFold(t, "reset") && execx.ContainsFold(t, "with paths")) ||
-		execx.ContainsFold(t, "cannot be used with") ||
-		execx.ContainsFold(t, "cannot be used together") ||
-		(execx.ContainsFold(t, "needs the paths") && execx.ContainsFold(t, "check out")) ||
-		execx.ContainsFold(t, "only one reference expected") ||
-		execx.ContainsFold(t, "needed a single revision") ||
-		execx.ContainsFold(t, "missing required") ||
-		execx.ContainsFold(t, "missing colon separator")
-}
-
-func isHelpInvocationArgs(args []string) bool {
-	if len(args) > 0 && args[0] == "help" {
-		return true
+	if m, ok := matchUsageish(p, args, res.ExitCode, res); ok {
+		return m
 	}
-	for _, a := range args {
-		switch a {
-		case "-h", "--help", "-help":
-			return true
-		}
+	if res.ExitCode == 0 {
+		return profile.Match{Class: profile.ClassOK}
 	}
-	return false
+	return p.ClassifyFailure(res.ExitCode, res.StdoutTail+res.StderrTail+res.CombinedTail)
 }
 
-func pickKnownGood(cands []store.SuccessCandidate, argvSafe []string) []string {
-	if len(argvSafe) == 0 {
-		return nil
-	}
-
-	want := wantTokens(argvSafe)
-
-	var best []string
-	bestScore := 0
-	bestLast := time.Time{}
+func isUsageish(tool string, args []string, code int, res execx.Result) bool {
+	_, ok := matchUsageish(profile.Load(tool), args, code, res)
+	return ok
+}
 
-	for _, c := range cands {
-		if len(c.Argv) == 0 {
-			continue
-		}
-		if slicesEqual(c.Argv, argvSafe) {
-			continue
+// matchUsageish classifies an invocation using the tool's profile and reports
+// which rule matched.
+func matchUsageish(p profile.Profile, args []string, code int, res execx.Result) (profile.Match, bool) {
+	if code == 0 {
+		// Some tools print usage/errors but still exit 0. Don't treat explicit help
+		// invocations as failures. Be conservative here because many tools can emit
+		// structured output (e.g., JSON) that may contain strings like "Usage:".
+		if p.IsHelpInvocation(args) {
+			return profile.Match{}, false
 		}
-		if containsRedacted(c.Argv) {
-			continue
+		return p.MatchUsageOnSuccess(successOutput(res))
+	}
+	text := res.StdoutTail + res.StderrTail + res.CombinedTail
+	if p.IsUsageExitCode(code) {
+		// Prefer the pattern (and line) that explains the exit code, if any.
+		if m, ok := p.MatchUsage(text); ok {
+			return m, true
 		}
+		return profile.Match{Rule: fmt.Sprintf("exit-code-%d", code), Class: profile.ClassUsage}, true
+	}
+	return p.MatchUsage(text)
+}
 
-		match := countArgMatches(want, c.Argv)
-		if match == 0 {
-			continue
-		}
-		prefix := commonPrefixLen(argvSafe, c.Argv)
-		score := match*1000 + prefix*10 + minInt(c.Count, 50)
+func looksUsageishOnSuccess(p profile.Profile, res execx.Result) bool {
+	_, ok := p.MatchUsageOnSuccess(successOutput(res))
+	return ok
+}
 
-		if score > bestScore || (score == bestScore && c.Last.After(bestLast)) {
-			best = c.Argv
-			bestScore = score
-			bestLast = c.Last
+// successOutput picks the output that may carry usage errors on exit=0.
+func successOutput(res execx.Result) string {
+	switch {
+	case res.Mode == "pipes":
+		return res.StderrTail
+	case res.Mode == "pty":
+		// stdout and stderr shared a terminal and can't be told apart.
+		return res.CombinedTail
+	case strings.HasPrefix(res.Mode, "pty:"):
+		// Per-stream layout: stderr was captured apart, on a PTY or a pipe.
+		return res.StderrTail
+	default:
+		if strings.TrimSpace(res.StderrTail) == "" {
+			return res.CombinedTail
 		}
+		return res.StderrTail
 	}
+}
 
-	return best
+func pickKnownGood(cands []store.SuccessCandidate, argvSafe []string) []string {
+	ranked := rankKnownGood(cands, argvSafe)
+	if len(ranked) == 0 {
+		return nil
+	}
+	return ranked[0].Argv
 }
 
 func wantTokens(argvSafe []string) []string {
@@ -581,30 +513,105 @@ func minInt(a, b int) int {
 	return b
 }
 
-func suggestKnownGood(tool, ctxKey string, argvSafe []string) {
+func suggestKnownGood(tool, ctxKey string, argvSafe []string, hints usageHints) {
 	if err := store.WithDB(func(db *store.DB) error {
-		cands, err := db.ListSuccessCandidates(tool, ctxKey, 200)
+		sugs, err := rankSuggestions(db, tool, ctxKey, argvSafe, hints, suggestionCount())
 		if err != nil {
 			return err
 		}
-		if len(cands) == 0 {
-			suggestNoKnownGood(tool)
+		if len(sugs) == 0 {
+			suggestNoKnownGood(tool, argvSafe, hints)
+			return nil
+		}
+		now := time.Now()
+		if hints.Agent != "" {
+			printAgentSuggestions(sugs, hints, now)
 			return nil
 		}
-		argv := pickKnownGood(cands, argvSafe)
+		fmt.Fprintln(os.Stderr, suggestionHeader(sugs[0]))
+		u := ui.New(os.Stderr)
+		for _, s := range sugs {
+			cmd, why := formatSuggestion(s, hints, now)
+			fmt.Fprintln(os.Stderr, "  "+cmd)
+			if s.Patched {
+				fmt.Fprintln(os.Stderr, "    "+tokenDiff(u, argvSafe, s.Argv))
+			}
+			if why != "" {
+				fmt.Fprintln(os.Stderr, "    "+why)
+			}
+		}
+		return nil
+	}); err != nil {
+		_ = err // best-effort
+	}
+}
+
+// suggestLastLogin surfaces the most recent successful login command for tool
+// (per the profile's login_tokens) after an auth failure.
+func suggestLastLogin(p profile.Profile, tool, ctxKey string, hints usageHints) {
+	if err := store.WithDB(func(db *store.DB) error {
+		cands, err := db.ListSuccessCandidates(tool, ctxKey, 200)
+		if err != nil {
+			return err
+		}
+		argv := pickLogin(cands, p.LoginTokens)
 		if len(argv) == 0 {
-			suggestNoKnownGood(tool)
 			return nil
 		}
-		fmt.Fprintln(os.Stderr, "ackchyually: suggestion (previous success in this repo):")
-		fmt.Fprintln(os.Stderr, "  "+execx.ShellJoin(argv))
+		fmt.Fprintln(os.Stderr, "ackchyually: auth failure; last successful login in this repo:")
+		fmt.Fprintln(os.Stderr, "  "+execx.ShellJoin(hints.command(argv)))
 		return nil
 	}); err != nil {
 		_ = err // best-effort
 	}
 }
 
-func suggestNoKnownGood(tool string) {
+// pickLogin returns the most recent candidate containing the earliest-listed
+// login token. cands are ordered most recent first.
+func pickLogin(cands []store.SuccessCandidate, tokens []string) []string {
+	for _, tok := range tokens {
+		for _, c := range cands {
+			if len(c.Argv) < 2 || containsRedacted(c.Argv) {
+				continue
+			}
+			for _, a := range c.Argv[1:] {
+				if strings.EqualFold(a, tok) {
+					return c.Argv
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// printAgentSuggestions prints one line per suggestion, each a runnable shell
+// line with the reasons as a trailing comment. Agents read every line of
+// output, so the header, diff and indentation are left out.
+func printAgentSuggestions(sugs []Suggestion, hints usageHints, now time.Time) {
+	for i, s := range sugs {
+		label := "suggestion"
+		if i > 0 {
+			label = "alternative"
+		}
+		cmd, why := formatSuggestion(s, hints, now)
+		if why != "" {
+			cmd += "  # " + why
+		}
+		fmt.Fprintf(os.Stderr, "ackchyually: %s: %s\n", label, cmd)
+	}
+}
+
+func suggestNoKnownGood(tool string, argvSafe []string, hints usageHints) {
+	if hints.Agent != "" {
+		// Agents tend to retry variations of a failing command; point them at
+		// the tool's own documentation instead.
+		cmd := execx.ShellJoin(hints.command([]string{tool}))
+		if len(argvSafe) > 1 && isCommandWord(argvSafe[1]) {
+			cmd += " " + argvSafe[1]
+		}
+		fmt.Fprintf(os.Stderr, "ackchyually: no successful %q command recorded in this repo to suggest from; run `%s --help` for the valid subcommands and flags instead of guessing\n", cmd, cmd)
+		return
+	}
 	if os.Getenv("ACKCHYUALLY_TEST_FORCE_TTY") != "true" && !term.IsTerminal(int(os.Stderr.Fd())) {
 		return
 	}
@@ -613,18 +620,23 @@ func suggestNoKnownGood(tool string) {
 }
 
 func autoExecKnownSuccessEnabled() bool {
-	v := strings.TrimSpace(strings.ToLower(os.Getenv("ACKCHYUALLY_AUTO_EXEC")))
-	return v == "known_success"
+	return autoExecMode() == "known_success"
 }
 
-func autoExecKnownSuccess(tool, ctxKey string, argvSafe []string) (int, bool) {
+func autoExecKnownSuccess(tool, exe, ctxKey string, argvSafe []string) (int, bool) {
 	var cmd []string
 	if err := store.WithDB(func(db *store.DB) error {
-		cands, err := db.ListSuccessCandidates(tool, ctxKey, 200)
+		sugs, err := rankSuggestions(db, tool, ctxKey, argvSafe, usageHints{}, 0)
 		if err != nil {
 			return err
 		}
-		cmd = pickKnownGood(cands, argvSafe)
+		// Only run commands that actually succeeded before.
+		for _, s := range sugs {
+			if !s.Patched || s.Uses > 0 {
+				cmd = s.Argv
+				break
+			}
+		}
 		return nil
 	}); err != nil {
 		return 0, false
@@ -641,8 +653,8 @@ func autoExecKnownSuccess(tool, ctxKey string, argvSafe []string) (int, bool) {
 	}
 
 	fmt.Fprintln(os.Stderr, "ackchyually: auto-exec (known_success):")
-	fmt.Fprintln(os.Stderr, "  "+execx.ShellJoin(cmd))
-	return runShim(cmd[0], cmd[1:], false), true
+	fmt.Fprintln(os.Stderr, "  "+execx.ShellJoin(commandLine(runPrefix(tool, exe), cmd)))
+	return rerun(tool, exe, cmd), true
 }
 
 func containsRedacted(argv []string) bool {
@@ -57,7 +57,7 @@ func TestAutoExecKnownSuccess_Executes(t *testing.T) {
 	// If I pass "echo helo", and "echo hello" is in DB.
 
 	code, out, errOut := captureStdoutStderr(t, func() int {
-		c, ok := autoExecKnownSuccess("echo", ctxKey, []string{"echo", "helo"})
+		c, ok := autoExecKnownSuccess("echo", "", ctxKey, []string{"echo", "helo"})
 		if !ok {
 			return -1
 		}
@@ -85,7 +85,7 @@ func TestAutoExecKnownSuccess_NoMatch(t *testing.T) {
 	// No seed data
 
 	code, _, _ := captureStdoutStderr(t, func() int {
-		c, ok := autoExecKnownSuccess("git", ctxKey, []string{"git", "st"})
+		c, ok := autoExecKnownSuccess("git", "", ctxKey, []string{"git", "st"})
 		if ok {
 			return c
 		}
@@ -105,7 +105,7 @@ func TestAutoExecKnownSuccess_Ambiguous(t *testing.T) {
 	seedInvocation(t, ctxKey, "git", []string{"git", "stash"}, now, 0)
 
 	code, _, _ := captureStdoutStderr(t, func() int {
-		c, ok := autoExecKnownSuccess("git", ctxKey, []string{"git", "st"})
+		c, ok := autoExecKnownSuccess("git", "", ctxKey, []string{"git", "st"})
 		if ok {
 			return c
 		}
@@ -126,7 +126,7 @@ func TestAutoExecKnownSuccess_Redacted(t *testing.T) {
 	seedInvocation(t, ctxKey, "curl", []string{"curl", "<redacted>"}, time.Now(), 0)
 
 	code, _, _ := captureStdoutStderr(t, func() int {
-		c, ok := autoExecKnownSuccess("curl", ctxKey, []string{"curl", "foo"})
+		c, ok := autoExecKnownSuccess("curl", "", ctxKey, []string{"curl", "foo"})
 		if ok {
 			return c
 		}
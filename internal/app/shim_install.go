@@ -9,6 +9,8 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/joelklabo/ackchyually/internal/config"
+	"github.com/joelklabo/ackchyually/internal/contextkey"
 	"github.com/joelklabo/ackchyually/internal/execx"
 	"github.com/joelklabo/ackchyually/internal/ui"
 )
@@ -117,6 +119,17 @@ func shimDoctor() int { //nolint:gocyclo
 	fmt.Printf("binary:   %s\n", ackExe)
 	fmt.Printf("shim dir: %s\n", shimDir)
 	fmt.Printf("db:       %s\n", dbPath)
+	fmt.Printf("config:   %s\n", config.Path())
+	if reason, ok := detectOptOut(contextkey.Detect()); ok {
+		fmt.Printf("privacy:  %s (%s)\n", u.Warn("opted out"), reason)
+	} else {
+		fmt.Println("privacy:  recording in this context")
+	}
+	if shimsOff() {
+		fmt.Printf("shims:    %s (ACKCHYUALLY_OFF); every shim execs the real tool\n", u.Warn("off"))
+	} else if cfg, err := config.Load(); err == nil && len(cfg.Passthrough.Tools) > 0 {
+		fmt.Printf("shims:    passthrough for %s\n", strings.Join(cfg.Passthrough.Tools, ", "))
+	}
 	fmt.Println()
 
 	exitCode := 0
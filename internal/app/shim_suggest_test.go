@@ -1,6 +1,7 @@
 package app
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -40,3 +41,32 @@ func TestPickKnownGood_MatchesAttachedNumericShortFlag(t *testing.T) {
 		t.Fatalf("pickKnownGood=%q want %q", got, want)
 	}
 }
+
+func TestRankKnownGood_ExplainsScore(t *testing.T) {
+	now := time.Date(2025, 12, 15, 12, 0, 0, 0, time.UTC)
+
+	argvBad := []string{"git", "log", "--prety=oneline"}
+	cands := []store.SuccessCandidate{
+		{Argv: []string{"git", "log", "--oneline"}, Count: 3, Last: now.Add(-26 * time.Hour)},
+		{Argv: []string{"git", "log", "--pretty=oneline"}, Count: 14, Last: now.Add(-2 * time.Hour)},
+		{Argv: []string{"git", "status"}, Count: 40, Last: now},
+	}
+
+	got := rankKnownGood(cands, argvBad)
+	if len(got) != 2 {
+		t.Fatalf("rankKnownGood returned %d suggestions, want 2: %+v", len(got), got)
+	}
+	top := got[0]
+	if !slicesEqual(top.Argv, []string{"git", "log", "--pretty=oneline"}) {
+		t.Fatalf("top = %q", top.Argv)
+	}
+	if top.Score != top.Match*1000+top.Prefix*10+top.Uses {
+		t.Fatalf("score breakdown does not add up: %+v", top)
+	}
+	if r := strings.Join(top.Reasons(now), "; "); r != "fixes --prety→--pretty; used 14×, last 2h ago" {
+		t.Fatalf("top reasons = %q", r)
+	}
+	if r := strings.Join(got[1].Reasons(now), "; "); r != "same subcommand; used 3×, last 26h ago" {
+		t.Fatalf("second reasons = %q", r)
+	}
+}
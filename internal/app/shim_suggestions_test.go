@@ -16,7 +16,7 @@ func TestSuggestKnownGood(t *testing.T) {
 
 	// Use a typo that is long enough for fuzzy matching (>= 3 chars)
 	code, _, errOut := captureStdoutStderr(t, func() int {
-		suggestKnownGood("git", ctxKey, []string{"git", "statu"})
+		suggestKnownGood("git", ctxKey, []string{"git", "statu"}, usageHints{})
 		return 0
 	})
 
@@ -41,7 +41,7 @@ func TestSuggestKnownGood_NoGoodMatch(t *testing.T) {
 
 	// Call with "git status" (very different from commit)
 	code, _, errOut := captureStdoutStderr(t, func() int {
-		suggestKnownGood("git", ctxKey, []string{"git", "status"})
+		suggestKnownGood("git", ctxKey, []string{"git", "status"}, usageHints{})
 		return 0
 	})
 
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/joelklabo/ackchyually/internal/execx"
+	"github.com/joelklabo/ackchyually/internal/profile"
 )
 
 func TestIsUsageish(t *testing.T) {
@@ -84,7 +85,7 @@ func TestIsUsageish(t *testing.T) {
 				StderrTail:   tt.stderr,
 				CombinedTail: combined,
 			}
-			got := isUsageish(tt.args, tt.exitCode, res)
+			got := isUsageish("git", tt.args, tt.exitCode, res)
 			if got != tt.want {
 				t.Errorf("isUsageish(%v, %d, %q, %q) = %v, want %v", tt.args, tt.exitCode, tt.stdout, tt.stderr, got, tt.want)
 			}
@@ -124,7 +125,7 @@ func TestLooksUsageishOnSuccess(t *testing.T) {
 			}
 		}
 
-		got := looksUsageishOnSuccess(res)
+		got := looksUsageishOnSuccess(profile.Load("git"), res)
 		if got != tt.want {
 			t.Logf("DEBUG: output=%q stderr=%q mode=%q", tt.stdout, tt.stderr, res.Mode)
 			t.Errorf("looksUsageishOnSuccess(%q, %q) = %v, want %v", tt.stdout, tt.stderr, got, tt.want)
@@ -7,7 +7,7 @@ import (
 )
 
 func TestIsUsageish_Exit0HelpInvocationIgnored(t *testing.T) {
-	got := isUsageish([]string{"--help"}, 0, execx.Result{CombinedTail: "Usage: tool [flags]\n"})
+	got := isUsageish("tool", []string{"--help"}, 0, execx.Result{CombinedTail: "Usage: tool [flags]\n"})
 	if got {
 		t.Fatalf("got true want false")
 	}
@@ -15,6 +15,7 @@ func TestIsUsageish_Exit0HelpInvocationIgnored(t *testing.T) {
 
 func TestIsUsageish_Exit0ErrorOutputStillCounts(t *testing.T) {
 	got := isUsageish(
+		"curl",
 		[]string{"-fsSL", "-o", "/dev/null", "-w", "%{fial}", "file:///etc/hosts"},
 		0,
 		execx.Result{StderrTail: "curl: unknown --write-out variable: 'fial'\n"},
@@ -26,6 +27,7 @@ func TestIsUsageish_Exit0ErrorOutputStillCounts(t *testing.T) {
 
 func TestIsUsageish_Exit0_JSONOutputContainingErrorStringsIgnored(t *testing.T) {
 	got := isUsageish(
+		"tool",
 		[]string{"list", "--json"},
 		0,
 		execx.Result{
@@ -39,8 +41,30 @@ func TestIsUsageish_Exit0_JSONOutputContainingErrorStringsIgnored(t *testing.T)
 	}
 }
 
+func TestIsUsageish_Exit0_SeparateStderrPTY(t *testing.T) {
+	// stdout that merely mentions an error is output, not a usage failure...
+	got := isUsageish("tool", []string{"grep", "flag"}, 0, execx.Result{
+		Mode:         "pty:in,out,err",
+		StdoutTail:   "unknown flag: --jsn\n",
+		CombinedTail: "unknown flag: --jsn\n",
+	})
+	if got {
+		t.Fatalf("stdout-only match: got true want false")
+	}
+	// ...but the same line on stderr is.
+	got = isUsageish("tool", []string{"do", "--jsn"}, 0, execx.Result{
+		Mode:         "pty:in,out,err",
+		StderrTail:   "unknown flag: --jsn\n",
+		CombinedTail: "unknown flag: --jsn\n",
+	})
+	if !got {
+		t.Fatalf("stderr match: got false want true")
+	}
+}
+
 func TestIsUsageish_Exit0_YAMLOutputWithUsageKeyIgnored(t *testing.T) {
 	got := isUsageish(
+		"tool",
 		[]string{"list", "--yaml"},
 		0,
 		execx.Result{
@@ -55,6 +79,7 @@ func TestIsUsageish_Exit0_YAMLOutputWithUsageKeyIgnored(t *testing.T) {
 
 func TestIsUsageish_Exit0_LogOutputWithErrorPrefixIgnored(t *testing.T) {
 	got := isUsageish(
+		"tool",
 		[]string{"logs"},
 		0,
 		execx.Result{
@@ -69,6 +94,7 @@ func TestIsUsageish_Exit0_LogOutputWithErrorPrefixIgnored(t *testing.T) {
 
 func TestIsUsageish_Exit0_ANSIPrefixedErrorDetected(t *testing.T) {
 	got := isUsageish(
+		"tool",
 		[]string{"do", "--badd"},
 		0,
 		execx.Result{
@@ -83,6 +109,7 @@ func TestIsUsageish_Exit0_ANSIPrefixedErrorDetected(t *testing.T) {
 
 func TestIsUsageish_Exit2_UnexpectedArgumentDetected(t *testing.T) {
 	got := isUsageish(
+		"tool",
 		[]string{"run", "--jsn"},
 		2,
 		execx.Result{
@@ -93,3 +120,12 @@ func TestIsUsageish_Exit2_UnexpectedArgumentDetected(t *testing.T) {
 		t.Fatalf("got false want true")
 	}
 }
+
+func TestIsUsageish_ToolProfileExitCode(t *testing.T) {
+	if !isUsageish("git", []string{"log", "--bogus"}, 129, execx.Result{}) {
+		t.Fatal("git exit 129: got false want true")
+	}
+	if isUsageish("script", []string{"run"}, 129, execx.Result{}) {
+		t.Fatal("script exit 129 without usage output: got true want false")
+	}
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joelklabo/ackchyually/internal/contextkey"
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+func statsCmd(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	all := fs.Bool("all", false, "count across all contexts")
+	perf := fs.Bool("perf", false, "show the slowest commands and duration regressions instead")
+	versions := fs.Bool("versions", false, "show the tool versions each command succeeded and failed on instead")
+	tool := fs.String("tool", "", "with --perf or --versions: only this tool")
+	limit := fs.Int("limit", 10, "with --perf: max slowest commands to show")
+	minVersion := fs.String("min", "", "with --versions and --tool: only commands that never succeeded below this version")
+	if err := parseFlags(fs, args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: ackchyually stats [--all] [--perf [--tool <tool>] [--limit <n>]] [--versions [--tool <tool> [--min <version>]]]")
+		return 2
+	}
+	ctxKey := contextkey.Detect()
+	if *all {
+		ctxKey = ""
+	}
+	if *perf {
+		return statsPerfImpl(ctxKey, *tool, *limit)
+	}
+	if *versions {
+		return statsVersionsImpl(ctxKey, *tool, *minVersion)
+	}
+	return statsImpl(ctxKey)
+}
+
+func statsImpl(ctxKey string) int {
+	var counts []store.ClassCount
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		counts, err = db.ClassCounts(ctxKey)
+		return err
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "ackchyually:", err)
+		return 1
+	}
+
+	if ctxKey == "" {
+		fmt.Println("context: (all)")
+	} else {
+		fmt.Printf("context: %s\n", ctxKey)
+	}
+	fmt.Println()
+	if len(counts) == 0 {
+		fmt.Println("(no invocations recorded)")
+		return 0
+	}
+	printClassTable(counts, "tool", func(c store.ClassCount) string { return c.Tool })
+	fmt.Println()
+	printClassTable(counts, "agent", func(c store.ClassCount) string {
+		if c.Agent == "" {
+			return "-"
+		}
+		return c.Agent
+	})
+	return 0
+}
+
+// printClassTable prints one row per key (tool or agent) with a column per
+// failure class. Rows recorded before classes (or agents) existed are counted
+// under "-".
+func printClassTable(counts []store.ClassCount, heading string, key func(store.ClassCount) string) {
+	var keys []string
+	byKey := map[string]map[string]int{}
+	used := map[string]bool{}
+	for _, c := range counts {
+		k := key(c)
+		if byKey[k] == nil {
+			byKey[k] = map[string]int{}
+			keys = append(keys, k)
+		}
+		class := c.Class
+		if class == "" {
+			class = "-"
+		}
+		byKey[k][class] += c.Count
+		used[class] = true
+	}
+	sort.Strings(keys)
+
+	var cols []string
+	for _, c := range append(append([]string{}, profile.Classes...), "-") {
+		if used[c] {
+			cols = append(cols, c)
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\ttotal\t%s\n", heading, strings.Join(cols, "\t"))
+	for _, t := range keys {
+		total := 0
+		row := make([]string, 0, len(cols))
+		for _, c := range cols {
+			n := byKey[t][c]
+			total += n
+			row = append(row, strconv.Itoa(n))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", t, total, strings.Join(row, "\t"))
+	}
+	_ = tw.Flush()
+}
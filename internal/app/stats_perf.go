@@ -0,0 +1,130 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/execx"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+// A command regressed after a tool upgrade when its average duration under
+// the newest binary is at least regressionFactor times, and regressionMinMS
+// more than, under the binary before it.
+const (
+	regressionFactor = 1.5
+	regressionMinMS  = 100
+)
+
+type regression struct{ before, after store.VersionTiming }
+
+func statsPerfImpl(ctxKey, tool string, limit int) int {
+	var slow []store.CommandTiming
+	var timings []store.VersionTiming
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		if slow, err = db.SlowestCommands(ctxKey, tool, limit); err != nil {
+			return err
+		}
+		timings, err = db.TimingsByToolVersion(ctxKey, tool)
+		return err
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "ackchyually:", err)
+		return 1
+	}
+
+	if ctxKey == "" {
+		fmt.Println("context: (all)")
+	} else {
+		fmt.Printf("context: %s\n", ctxKey)
+	}
+	fmt.Println()
+	if len(slow) == 0 {
+		fmt.Println("(no invocations recorded)")
+		return 0
+	}
+
+	fmt.Println("slowest commands:")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "runs\tavg\tmax\tcpu\trss\tcommand")
+	for _, c := range slow {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", c.Runs, formatMS(c.AvgMS), formatMS(c.MaxMS),
+			formatMS(c.AvgCPUMS), formatSize(c.MaxRSSKB*1024), argvString(c.Tool, c.ArgvJSON))
+	}
+	_ = tw.Flush()
+
+	fmt.Println()
+	regs := findRegressions(timings)
+	if len(regs) == 0 {
+		fmt.Println("no duration regressions after a tool upgrade")
+		return 0
+	}
+	fmt.Println("duration regressions after a tool upgrade:")
+	tw = tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "before\tafter\tcommand")
+	for _, r := range regs {
+		fmt.Fprintf(tw, "%s (%s, %d runs)\t%s (%s, %d runs)\t%s\n",
+			formatMS(r.before.AvgMS), versionLabel(r.before), r.before.Runs,
+			formatMS(r.after.AvgMS), versionLabel(r.after), r.after.Runs,
+			argvString(r.after.Tool, r.after.ArgvJSON))
+	}
+	_ = tw.Flush()
+	return 0
+}
+
+// findRegressions compares each command's newest tool binary with the one
+// before it. timings is grouped by command, binaries oldest first.
+func findRegressions(timings []store.VersionTiming) []regression {
+	var out []regression
+	for i := 1; i < len(timings); i++ {
+		before, after := timings[i-1], timings[i]
+		if before.Tool != after.Tool || before.ArgvJSON != after.ArgvJSON {
+			continue
+		}
+		if i+1 < len(timings) && timings[i+1].Tool == after.Tool && timings[i+1].ArgvJSON == after.ArgvJSON {
+			continue // not the newest binary yet
+		}
+		if float64(after.AvgMS) >= regressionFactor*float64(before.AvgMS) && after.AvgMS-before.AvgMS >= regressionMinMS {
+			out = append(out, regression{before: before, after: after})
+		}
+	}
+	return out
+}
+
+func versionLabel(v store.VersionTiming) string {
+	switch {
+	case v.SemVer != "":
+		return v.Tool + " " + v.SemVer
+	case v.Version != "":
+		first, _, _ := strings.Cut(v.Version, "\n")
+		return first
+	default:
+		return "tool #" + strconv.FormatInt(v.ToolID, 10)
+	}
+}
+
+func argvString(tool, argvJSON string) string {
+	return execx.ShellJoin(invocationArgv(store.Invocation{Tool: tool, ArgvJSON: argvJSON}))
+}
+
+func formatMS(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// formatSize renders a byte count in binary units ("512B", "12.0K", "48.3M").
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + "B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(n)/float64(div), "KMGT"[exp])
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+func TestStatsPerf_SlowestAndRegressions(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	now := time.Now()
+	if err := store.WithDB(func(db *store.DB) error {
+		oldID, err := db.UpsertTool(store.ToolIdentity{ExePath: "/bin/git", SHA256: "old", VersionStr: "git version 2.39.0"})
+		if err != nil {
+			return err
+		}
+		newID, err := db.UpsertTool(store.ToolIdentity{ExePath: "/bin/git", SHA256: "new", VersionStr: "git version 2.43.0"})
+		if err != nil {
+			return err
+		}
+		for i, inv := range []store.Invocation{
+			{ToolID: oldID, ArgvJSON: `["git","status"]`, DurationMS: 100},
+			{ToolID: newID, ArgvJSON: `["git","status"]`, DurationMS: 900, CPUUserMS: 300, MaxRSSKB: 10240},
+			{ToolID: oldID, ArgvJSON: `["git","log"]`, DurationMS: 40},
+			{ToolID: newID, ArgvJSON: `["git","log"]`, DurationMS: 45},
+		} {
+			inv.At = now.Add(time.Duration(i) * time.Second)
+			inv.ContextKey, inv.Tool, inv.Mode, inv.Class = ctxKey, "git", "pipes", profile.ClassOK
+			if err := db.InsertInvocation(inv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	code, out, _ := captureStdoutStderr(t, func() int { return statsCmd([]string{"--perf", "--tool", "git"}) })
+	if code != 0 {
+		t.Fatalf("stats --perf returned %d", code)
+	}
+	slowest, regressions, ok := strings.Cut(out, "duration regressions after a tool upgrade:")
+	if !ok {
+		t.Fatalf("stats --perf output missing regressions:\n%s", out)
+	}
+	if i, j := strings.Index(slowest, "git status"), strings.Index(slowest, "git log"); i < 0 || j < 0 || i > j {
+		t.Fatalf("expected git status listed first as the slowest:\n%s", out)
+	}
+	if !strings.Contains(slowest, "10.0M") {
+		t.Fatalf("expected the peak RSS in the slowest table:\n%s", out)
+	}
+	if !strings.Contains(regressions, "git status") || !strings.Contains(regressions, "git v2.43.0") || strings.Contains(regressions, "git log") {
+		t.Fatalf("expected only git status to be reported as regressed:\n%s", out)
+	}
+}
+
+func TestHistory_ShowsResources(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	if err := store.WithDB(func(db *store.DB) error {
+		return db.InsertInvocation(store.Invocation{
+			At: time.Now(), DurationMS: 1200, ContextKey: ctxKey, Tool: "make", ArgvJSON: `["make","build"]`, Mode: "pipes",
+			CPUUserMS: 700, CPUSysMS: 50, MaxRSSKB: 51200, StdoutBytes: 2048, StderrBytes: 12,
+		})
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	_, out, _ := captureStdoutStderr(t, func() int { return historyCmd(nil) })
+	for _, want := range []string{"cpu 750ms", "rss 50.0M", "out 2.0K", "err 12B", "make build"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("history output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{0: "0B", 1023: "1023B", 1024: "1.0K", 1536: "1.5K", 5 << 20: "5.0M", 3 << 30: "3.0G"} {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
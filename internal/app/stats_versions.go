@@ -0,0 +1,115 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/joelklabo/ackchyually/internal/store"
+	"github.com/joelklabo/ackchyually/internal/toolversion"
+)
+
+// versionSpan is the range of tool versions a command succeeded (or failed)
+// on.
+type versionSpan struct{ lo, hi string }
+
+func (s *versionSpan) add(v string) {
+	if s.lo == "" || toolversion.Compare(v, s.lo) < 0 {
+		s.lo = v
+	}
+	if s.hi == "" || toolversion.Compare(v, s.hi) > 0 {
+		s.hi = v
+	}
+}
+
+func (s versionSpan) String() string {
+	switch {
+	case s.lo == "":
+		return "-"
+	case s.lo == s.hi:
+		return s.lo
+	default:
+		return s.lo + "–" + s.hi
+	}
+}
+
+type commandVersions struct {
+	tool, argvJSON string
+	ok, failed     versionSpan
+}
+
+// commandVersionSpans folds outcomes (grouped by command) into the versions
+// each command succeeded and failed on.
+func commandVersionSpans(outcomes []store.VersionOutcome) []commandVersions {
+	var out []commandVersions
+	for _, o := range outcomes {
+		if len(out) == 0 || out[len(out)-1].tool != o.Tool || out[len(out)-1].argvJSON != o.ArgvJSON {
+			out = append(out, commandVersions{tool: o.Tool, argvJSON: o.ArgvJSON})
+		}
+		c := &out[len(out)-1]
+		if o.OK > 0 {
+			c.ok.add(o.Version)
+		}
+		if o.Failed > 0 {
+			c.failed.add(o.Version)
+		}
+	}
+	return out
+}
+
+// statsVersionsImpl shows the tool versions each command succeeded and
+// failed on. With minVersion it keeps the commands that only ever succeeded
+// on tool >= minVersion.
+func statsVersionsImpl(ctxKey, tool, minVersion string) int {
+	minV := ""
+	if minVersion != "" {
+		v, ok := toolversion.Normalize(minVersion)
+		if !ok || tool == "" {
+			fmt.Fprintln(os.Stderr, "usage: ackchyually stats --versions --tool <tool> --min <version>")
+			return 2
+		}
+		minV = v
+	}
+
+	var outcomes []store.VersionOutcome
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		outcomes, err = db.OutcomesByToolVersion(ctxKey, tool)
+		return err
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "ackchyually:", err)
+		return 1
+	}
+
+	if ctxKey == "" {
+		fmt.Println("context: (all)")
+	} else {
+		fmt.Printf("context: %s\n", ctxKey)
+	}
+	fmt.Println()
+
+	var rows []commandVersions
+	for _, c := range commandVersionSpans(outcomes) {
+		if minV != "" && (c.ok.lo == "" || toolversion.Compare(c.ok.lo, minV) < 0) {
+			continue
+		}
+		rows = append(rows, c)
+	}
+	if len(rows) == 0 {
+		fmt.Println("(no invocations with a known tool version)")
+		return 0
+	}
+
+	if minV != "" {
+		fmt.Printf("commands that only ever succeeded on %s >= %s:\n", tool, minV)
+	} else {
+		fmt.Println("tool versions per command:")
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "succeeded on\tfailed on\tcommand")
+	for _, c := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.ok, c.failed, argvString(c.tool, c.argvJSON))
+	}
+	_ = tw.Flush()
+	return 0
+}
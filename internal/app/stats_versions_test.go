@@ -0,0 +1,67 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+func TestStatsVersions(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	now := time.Now()
+	if err := store.WithDB(func(db *store.DB) error {
+		ids := map[string]int64{}
+		for _, v := range []string{"gh version 2.38.0 (2023-11-13)", "gh version 2.40.1 (2023-12-13)", "gh version 2.44.1 (2024-02-16)"} {
+			id, err := db.UpsertTool(store.ToolIdentity{ExePath: "/bin/gh", SHA256: v, VersionStr: "gh " + v + "\nhttps://github.com/cli/cli/releases/latest"})
+			if err != nil {
+				return err
+			}
+			ids[strings.Fields(v)[2]] = id
+		}
+		for i, inv := range []store.Invocation{
+			{ToolID: ids["2.38.0"], ArgvJSON: `["gh","pr","view","--json","state"]`, ExitCode: 1},
+			{ToolID: ids["2.40.1"], ArgvJSON: `["gh","pr","view","--json","state"]`},
+			{ToolID: ids["2.44.1"], ArgvJSON: `["gh","pr","view","--json","state"]`},
+			{ToolID: ids["2.38.0"], ArgvJSON: `["gh","pr","list"]`},
+			{ToolID: ids["2.44.1"], ArgvJSON: `["gh","pr","list"]`},
+			{ArgvJSON: `["gh","auth","status"]`},
+		} {
+			inv.At = now.Add(time.Duration(i) * time.Second)
+			inv.ContextKey, inv.Tool, inv.Mode, inv.Class = ctxKey, "gh", "pipes", profile.ClassOK
+			if inv.ExitCode != 0 {
+				inv.Class = profile.ClassUsage
+			}
+			if err := db.InsertInvocation(inv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	code, out, errOut := captureStdoutStderr(t, func() int { return RunCLI([]string{"stats", "--versions"}) })
+	if code != 0 {
+		t.Fatalf("stats --versions exit=%d stderr=%q", code, errOut)
+	}
+	if !strings.Contains(out, "v2.38.0–v2.44.1") || !strings.Contains(out, "v2.40.1–v2.44.1  v2.38.0") || strings.Contains(out, "auth") {
+		t.Fatalf("unexpected stats --versions output:\n%s", out)
+	}
+
+	code, out, errOut = captureStdoutStderr(t, func() int {
+		return RunCLI([]string{"stats", "--versions", "--tool", "gh", "--min", "2.40"})
+	})
+	if code != 0 {
+		t.Fatalf("stats --versions --min exit=%d stderr=%q", code, errOut)
+	}
+	if !strings.Contains(out, "only ever succeeded on gh >= v2.40.0") || !strings.Contains(out, "gh pr view --json state") || strings.Contains(out, "gh pr list") {
+		t.Fatalf("unexpected stats --versions --min output:\n%s", out)
+	}
+
+	if code, _, _ := captureStdoutStderr(t, func() int { return RunCLI([]string{"stats", "--versions", "--min", "2.40"}) }); code != 2 {
+		t.Fatalf("--min without --tool: exit=%d, want 2", code)
+	}
+}
@@ -0,0 +1,347 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/config"
+	"github.com/joelklabo/ackchyually/internal/execx"
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+// Suggestion is a ranked replacement for a failing command together with the
+// score breakdown that put it there. Everything that prints or explains a
+// suggestion works from this, so the reasons shown always match the ranking.
+type Suggestion struct {
+	Argv []string
+
+	Score  int
+	Match  int // argument tokens shared with the failing command (incl. one-edit typos)
+	Prefix int // leading argv elements identical to the failing command
+	Uses   int // successful runs of Argv in this context
+	Last   time.Time
+
+	// Learned counts how often Argv fixed exactly this failing command before
+	// (see learnCorrection); learned suggestions rank above everything else.
+	Learned int
+	// Patched is the failing command with only the offending tokens fixed
+	// (see patchArgv). It ranks right after a learned correction.
+	Patched bool
+	// Source is where a patch's vocabulary came from when it is not history:
+	// vocabCompletions or vocabHelp (see toolVocabulary).
+	Source string
+
+	Fixes          []TokenFix
+	SameSubcommand bool
+}
+
+// TokenFix is a failing argument and the candidate argument it was a typo of.
+type TokenFix struct {
+	From string
+	To   string
+}
+
+// Reasons explains the suggestion in a few short phrases, strongest first.
+func (s Suggestion) Reasons(now time.Time) []string {
+	var out []string
+	switch {
+	case s.Learned > 1:
+		out = append(out, fmt.Sprintf("fixed this exact command %d× before", s.Learned))
+	case s.Learned == 1:
+		out = append(out, "fixed this exact command last time")
+	}
+	if s.Patched {
+		out = append(out, "keeps the rest of what you typed")
+	}
+	if len(s.Fixes) > 0 {
+		parts := make([]string, 0, len(s.Fixes))
+		for _, f := range s.Fixes {
+			parts = append(parts, f.From+"→"+f.To)
+		}
+		out = append(out, "fixes "+strings.Join(parts, ", "))
+	} else if s.SameSubcommand && s.Learned == 0 {
+		out = append(out, "same subcommand")
+	}
+	if s.Source != "" {
+		out = append(out, "valid per "+sourceLabel(s))
+	}
+	if s.Uses > 0 {
+		u := fmt.Sprintf("used %d×", s.Uses)
+		if !s.Last.IsZero() {
+			u += ", last " + ago(now.Sub(s.Last))
+		}
+		out = append(out, u)
+	}
+	return out
+}
+
+// Skip reasons recorded by judgeCandidates.
+const (
+	skipSameArgv  = "same as the failing command"
+	skipRedacted  = "contains <redacted>"
+	skipNoMatch   = "no argument tokens in common"
+	skipEmptyArgv = "empty argv"
+)
+
+// candidateVerdict is one candidate as rankKnownGood saw it: scored, or
+// skipped with the reason.
+type candidateVerdict struct {
+	Suggestion
+	Skip string
+}
+
+// judgeCandidates scores every candidate against the failing argv, keeping
+// the ones that were ruled out so `ackchyually why` can show them.
+func judgeCandidates(cands []store.SuccessCandidate, argvSafe []string) []candidateVerdict {
+	if len(argvSafe) == 0 {
+		return nil
+	}
+
+	want := wantTokens(argvSafe)
+	p := profile.Load(argvSafe[0])
+	sub := subcommandOf(p, argvSafe)
+
+	out := make([]candidateVerdict, 0, len(cands))
+	for _, c := range cands {
+		v := candidateVerdict{Suggestion: Suggestion{Argv: c.Argv, Uses: c.Count, Last: c.Last}}
+		switch {
+		case len(c.Argv) == 0:
+			v.Skip = skipEmptyArgv
+		case slicesEqual(c.Argv, argvSafe):
+			v.Skip = skipSameArgv
+		case containsRedacted(c.Argv):
+			v.Skip = skipRedacted
+		}
+		if v.Skip != "" {
+			out = append(out, v)
+			continue
+		}
+
+		v.Match = countArgMatches(want, c.Argv)
+		if v.Match == 0 {
+			v.Skip = skipNoMatch
+			out = append(out, v)
+			continue
+		}
+		v.Prefix = commonPrefixLen(argvSafe, c.Argv)
+		v.Score = v.Match*1000 + v.Prefix*10 + minInt(c.Count, 50)
+		v.Fixes = tokenFixes(argvSafe, c.Argv)
+		v.SameSubcommand = sub != "" && sub == subcommandOf(p, c.Argv)
+		out = append(out, v)
+	}
+	return out
+}
+
+// rankKnownGood returns the candidates sharing at least one argument token
+// with the failing argv, best first.
+func rankKnownGood(cands []store.SuccessCandidate, argvSafe []string) []Suggestion {
+	var out []Suggestion
+	for _, v := range judgeCandidates(cands, argvSafe) {
+		if v.Skip == "" {
+			out = append(out, v.Suggestion)
+		}
+	}
+	sortSuggestions(out)
+	return out
+}
+
+func sortSuggestions(s []Suggestion) {
+	sort.SliceStable(s, func(i, j int) bool {
+		if s[i].Score != s[j].Score {
+			return s[i].Score > s[j].Score
+		}
+		return s[i].Last.After(s[j].Last)
+	})
+}
+
+// usageHints is what the shim knows about a usage failure beyond its argv.
+type usageHints struct {
+	Line string // output line that classified the failure, if known
+	// Exe and ToolSHA identify the tool binary so its --help can be used as
+	// vocabulary when history has no fix; empty disables that.
+	Exe     string
+	ToolSHA string
+	// Run is how a printed command runs the tool (see runPrefix); nil when
+	// its name does.
+	Run []string
+	// Agent is who ran the command (detectAgent): "" for a person.
+	Agent string
+}
+
+// command is argv as the command line to print or report.
+func (h usageHints) command(argv []string) []string {
+	return commandLine(h.Run, argv)
+}
+
+// rankSuggestions returns up to n suggestions for argvSafe: a learned
+// correction first (if any), then argvSafe patched in place, then known-good
+// commands by similarity.
+func rankSuggestions(db *store.DB, tool, ctxKey string, argvSafe []string, hints usageHints, n int) ([]Suggestion, error) {
+	cands, err := db.ListSuccessCandidates(tool, ctxKey, 200)
+	if err != nil {
+		return nil, err
+	}
+	ranked := rankKnownGood(cands, argvSafe)
+
+	c, ok, err := db.LookupCorrection(ctxKey, tool, argvSafe)
+	if err != nil {
+		return nil, err
+	}
+	var out []Suggestion
+	if ok && !containsRedacted(c.FixedArgv) && !slicesEqual(c.FixedArgv, argvSafe) {
+		learned := takeSuggestion(&ranked, c.FixedArgv)
+		learned.Learned = c.Count
+		if learned.Last.IsZero() {
+			learned.Last = c.Last
+		}
+		if learned.Fixes == nil {
+			learned.Fixes = tokenFixes(argvSafe, c.FixedArgv)
+		}
+		out = append(out, learned)
+	}
+	if p, ok := patchSuggestion(db, argvSafe, hints, cands); ok && !containsRedacted(p.Argv) && !containsArgv(out, p.Argv) {
+		s := takeSuggestion(&ranked, p.Argv)
+		s.Patched, s.Source, s.Fixes = true, p.Source, p.Fixes
+		out = append(out, s)
+	}
+	out = append(out, ranked...)
+
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out, nil
+}
+
+// patchSuggestion patches argvSafe using the history vocabulary, falling back
+// to what the tool itself accepts when history has nothing one edit away.
+// Only the tool's cached vocabulary is used: the tool is never run here.
+func patchSuggestion(db *store.DB, argvSafe []string, hints usageHints, cands []store.SuccessCandidate) (Suggestion, bool) {
+	if patched, fixes, ok := patchArgv(argvSafe, hints.Line, cands); ok {
+		return Suggestion{Argv: patched, Patched: true, Fixes: fixes}, true
+	}
+	if hints.ToolSHA == "" || len(argvSafe) == 0 {
+		return Suggestion{}, false
+	}
+	v, source := toolVocabulary(&vocabCache{db: db, exe: hints.Exe, sha: hints.ToolSHA}, argvSafe)
+	if patched, fixes, ok := patchWithVocab(argvSafe, hints.Line, v); ok {
+		return Suggestion{Argv: patched, Patched: true, Source: source, Fixes: fixes}, true
+	}
+	return Suggestion{}, false
+}
+
+// takeSuggestion removes argv from ranked (keeping its score breakdown) or
+// returns a bare suggestion for it.
+func takeSuggestion(ranked *[]Suggestion, argv []string) Suggestion {
+	for i, s := range *ranked {
+		if slicesEqual(s.Argv, argv) {
+			*ranked = append((*ranked)[:i:i], (*ranked)[i+1:]...)
+			return s
+		}
+	}
+	return Suggestion{Argv: argv}
+}
+
+func containsArgv(sugs []Suggestion, argv []string) bool {
+	for _, s := range sugs {
+		if slicesEqual(s.Argv, argv) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenFixes pairs failing arguments with the candidate argument they look
+// like a typo of (e.g. --prety→--pretty).
+func tokenFixes(failing, cand []string) []TokenFix {
+	if len(failing) < 2 || len(cand) < 2 {
+		return nil
+	}
+	have := make(map[string]bool, len(cand))
+	for _, a := range cand[1:] {
+		have[strings.ToLower(a)] = true
+	}
+	var out []TokenFix
+	for _, a := range failing[1:] {
+		la := strings.ToLower(a)
+		if have[la] {
+			continue
+		}
+		if f, ok := typoTarget(la, cand[1:]); ok {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func typoTarget(arg string, cand []string) (TokenFix, bool) {
+	for _, av := range tokenVariants(arg) {
+		na, _ := normalizeFuzzyToken(av)
+		for _, c := range cand {
+			for _, v := range tokenVariants(c) {
+				if nv, _ := normalizeFuzzyToken(v); nv == na {
+					continue // same word, not a typo
+				}
+				if fuzzyTokenMatch(av, v) {
+					return TokenFix{From: av, To: v}, true
+				}
+			}
+		}
+	}
+	return TokenFix{}, false
+}
+
+func suggestionCount() int {
+	cfg, err := config.Load()
+	if err != nil {
+		return config.DefaultSuggestCount
+	}
+	return cfg.Suggest.N()
+}
+
+// suggestionHeader is the line printed above a list of suggestions, naming
+// where the top one came from.
+func suggestionHeader(top Suggestion) string {
+	switch {
+	case top.Learned > 0:
+		return "ackchyually: suggestion (what fixed this last time in this repo):"
+	case top.Source != "":
+		return "ackchyually: suggestion (patched using " + sourceLabel(top) + "):"
+	case top.Patched:
+		return "ackchyually: suggestion (patched from what you typed):"
+	default:
+		return "ackchyually: suggestion (previous success in this repo):"
+	}
+}
+
+// sourceLabel names a tool vocabulary for people: "kubectl completions",
+// "mytool --help".
+func sourceLabel(s Suggestion) string {
+	tool := ""
+	if len(s.Argv) > 0 {
+		tool = s.Argv[0] + " "
+	}
+	if s.Source == vocabHelp {
+		return tool + "--help"
+	}
+	return tool + s.Source
+}
+
+func formatSuggestion(s Suggestion, hints usageHints, now time.Time) (cmd, why string) {
+	return execx.ShellJoin(hints.command(s.Argv)), strings.Join(s.Reasons(now), "; ")
+}
+
+func ago(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	}
+}
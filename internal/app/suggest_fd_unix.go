@@ -0,0 +1,40 @@
+//go:build !windows
+
+package app
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/sys/unix"
+)
+
+// openSuggestFD writes to an inherited file descriptor (e.g. 3 from `3>file`
+// or a pipe the agent reads). The fd is used directly rather than through an
+// *os.File, whose finalizer would close it under nested runs still using it.
+func openSuggestFD(fd int) (io.Writer, error) {
+	if fd < 0 {
+		return nil, fmt.Errorf("invalid fd %d", fd)
+	}
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_GETFD, 0); err != nil {
+		return nil, fmt.Errorf("fd %d: %w", fd, err)
+	}
+	return fdWriter(fd), nil
+}
+
+type fdWriter int
+
+func (w fdWriter) Write(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		m, err := unix.Write(int(w), p[n:])
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		n += m
+	}
+	return n, nil
+}
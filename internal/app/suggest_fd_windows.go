@@ -0,0 +1,12 @@
+//go:build windows
+
+package app
+
+import (
+	"errors"
+	"io"
+)
+
+func openSuggestFD(int) (io.Writer, error) {
+	return nil, errors.New("not supported on Windows; use ACKCHYUALLY_SUGGEST_FILE")
+}
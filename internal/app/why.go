@@ -0,0 +1,255 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/contextkey"
+	"github.com/joelklabo/ackchyually/internal/execx"
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+	"github.com/joelklabo/ackchyually/internal/ui"
+)
+
+func whyCmd(args []string) int {
+	fs := flag.NewFlagSet("why", flag.ContinueOnError)
+	fs.Bool("last", false, "explain the most recent invocation in this context (default)")
+	if err := parseFlags(fs, args); err != nil {
+		return 2
+	}
+	switch fs.NArg() {
+	case 0:
+		return whyImpl(0)
+	case 1:
+		id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+		if err == nil && id > 0 {
+			return whyImpl(id)
+		}
+	}
+	fmt.Fprintln(os.Stderr, "usage: ackchyually why [--last|<invocation-id>]")
+	return 2
+}
+
+// whyImpl replays the classification and suggestion ranking for a recorded
+// invocation (id 0 = the most recent shim invocation in this context).
+func whyImpl(id int64) int {
+	ctxKey := contextkey.Detect()
+	u := ui.New(os.Stdout)
+
+	var inv store.Invocation
+	var correction store.Correction
+	var learned bool
+	var cands []store.SuccessCandidate
+	err := store.WithDB(func(db *store.DB) error {
+		var err error
+		if id == 0 {
+			inv, err = lastShimInvocation(db, ctxKey)
+		} else {
+			inv, err = db.GetInvocation(id)
+		}
+		if err != nil {
+			return err
+		}
+		correction, learned, err = db.LookupCorrection(inv.ContextKey, inv.Tool, invocationArgv(inv))
+		if err != nil {
+			return err
+		}
+		cands, err = db.ListSuccessCandidates(inv.Tool, inv.ContextKey, 200)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		if id == 0 {
+			fmt.Fprintln(os.Stderr, "ackchyually: no invocations recorded in this context yet")
+		} else {
+			fmt.Fprintf(os.Stderr, "ackchyually: no invocation with id %d\n", id)
+		}
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ackchyually:", err)
+		return 1
+	}
+
+	argv := invocationArgv(inv)
+	fmt.Printf("invocation %s\n", u.Bold("#"+strconv.FormatInt(inv.ID, 10)))
+	fmt.Printf("  command:  %s\n", execx.ShellJoin(argv))
+	fmt.Printf("  context:  %s\n", inv.ContextKey)
+	fmt.Printf("  at:       %s (%dms)\n", inv.At.Local().Format("2006-01-02 15:04:05"), inv.DurationMS)
+	fmt.Printf("  exit:     %d (%s mode)\n", inv.ExitCode, inv.Mode)
+	if inv.Signal != "" {
+		fmt.Printf("  signal:   %s\n", inv.Signal)
+	}
+	if r := strings.TrimSpace(formatResources(inv)); r != "" {
+		fmt.Printf("  usage:    %s\n", strings.Join(strings.Fields(r), " "))
+	}
+	fmt.Printf("  ran by:   %s\n", orDash(inv.Agent))
+	fmt.Printf("  recorded: %s\n", orDash(inv.Class))
+	fmt.Println()
+
+	m := replayClassification(inv, argv)
+	fmt.Println(u.Label("classification"))
+	fmt.Printf("  class: %s\n", m.Class)
+	fmt.Printf("  rule:  %s\n", orDash(m.Rule))
+	if m.Line != "" {
+		fmt.Printf("  line:  %s\n", m.Line)
+	}
+	fmt.Println()
+
+	switch m.Class {
+	case profile.ClassUsage:
+	case profile.ClassAuth:
+		fmt.Println("no argv suggestion: auth failures point at the last successful login instead")
+		return 0
+	default:
+		fmt.Printf("no suggestion: only usage failures get suggestions (class %s)\n", m.Class)
+		return 0
+	}
+
+	fmt.Println(u.Label("learned correction"))
+	if learned {
+		fmt.Printf("  %s (fixed it %d×, last %s)\n", execx.ShellJoin(correction.FixedArgv), correction.Count, ago(time.Since(correction.Last)))
+	} else {
+		fmt.Println("  none")
+	}
+	fmt.Println()
+
+	var patch Suggestion
+	var canPatch bool
+	var ranked []Suggestion
+	if err := store.WithDB(func(db *store.DB) error {
+		hints := usageHints{Line: m.Line, Exe: inv.ExePath}
+		if ti, err := db.GetToolByID(inv.ToolID); err == nil {
+			hints.ToolSHA = ti.SHA256
+		}
+		patch, canPatch = patchSuggestion(db, argv, hints, cands)
+		var err error
+		ranked, err = rankSuggestions(db, inv.Tool, inv.ContextKey, argv, hints, 1)
+		return err
+	}); err != nil {
+		_ = err // best-effort
+	}
+	fmt.Println(u.Label("patch"))
+	if canPatch {
+		fmt.Printf("  %s\n", tokenDiff(u, argv, patch.Argv))
+		_, why := formatSuggestion(patch, usageHints{}, time.Now())
+		fmt.Printf("  %s\n", u.Dim(why))
+	} else {
+		fmt.Println("  none (no flag or subcommand is one edit from one in history or --help)")
+	}
+	fmt.Println()
+
+	verdicts := judgeCandidates(cands, argv)
+	printVerdicts(u, verdicts)
+
+	// The winner is what the shim would suggest first now.
+	switch {
+	case len(ranked) > 0 && ranked[0].Learned > 0:
+		fmt.Printf("winner: %s (learned correction ranks above similarity)\n", execx.ShellJoin(ranked[0].Argv))
+	case len(ranked) > 0 && ranked[0].Patched:
+		fmt.Printf("winner: %s (patched in place ranks above similarity)\n", execx.ShellJoin(ranked[0].Argv))
+	case len(ranked) > 0:
+		fmt.Printf("winner: %s (highest score; ties go to the most recent)\n", execx.ShellJoin(ranked[0].Argv))
+	case len(cands) == 0:
+		fmt.Printf("no suggestion: no successful %s command recorded in this context\n", inv.Tool)
+	default:
+		fmt.Println("no suggestion: every candidate was skipped")
+	}
+	return 0
+}
+
+func printVerdicts(u ui.UI, verdicts []candidateVerdict) {
+	scored := make([]Suggestion, 0, len(verdicts))
+	var skipped []candidateVerdict
+	for _, v := range verdicts {
+		if v.Skip == "" {
+			scored = append(scored, v.Suggestion)
+		} else {
+			skipped = append(skipped, v)
+		}
+	}
+	sortSuggestions(scored)
+
+	fmt.Printf("%s (%d, replayed against current history)\n", u.Label("candidates"), len(verdicts))
+	now := time.Now()
+	for i, s := range scored {
+		fmt.Printf("  %2d. score=%-5d match=%d prefix=%d uses=%d  %s\n",
+			i+1, s.Score, s.Match, s.Prefix, s.Uses, execx.ShellJoin(s.Argv))
+		if _, why := formatSuggestion(s, usageHints{}, now); why != "" {
+			fmt.Printf("      %s\n", u.Dim(why))
+		}
+	}
+	for _, v := range skipped {
+		fmt.Printf("  %s %s: %s\n", u.Dim("skip"), execx.ShellJoin(v.Argv), v.Skip)
+	}
+	fmt.Println()
+}
+
+// replayClassification finds the rule and line behind a recorded invocation's
+// class by re-running the profile rules on its tails. An interrupt or a
+// full-screen tool doesn't show in the tails, so those come from the record.
+func replayClassification(inv store.Invocation, argv []string) profile.Match {
+	switch inv.Class {
+	case profile.ClassInterrupted:
+		return profile.Match{Class: inv.Class, Rule: "signal", Line: inv.Signal}
+	case profile.ClassTUI:
+		return profile.Match{Class: inv.Class, Rule: "alternate-screen"}
+	}
+	p := profile.Load(inv.Tool)
+	res := execx.Result{
+		ExitCode:     inv.ExitCode,
+		Mode:         inv.Mode,
+		StdoutTail:   inv.StdoutTail,
+		StderrTail:   inv.StderrTail,
+		CombinedTail: inv.CombinedTail,
+	}
+	if inv.Class == profile.ClassUsage && inv.ExitCode == 64 {
+		// runShim records usage errors on exit 0 as 64; replay the original.
+		res0 := res
+		res0.ExitCode = 0
+		if m, ok := matchUsageish(p, argv[1:], 0, res0); ok {
+			return m
+		}
+	}
+	m := classifyInvocation(p, argv[1:], res)
+	if inv.Class != "" && m.Class != inv.Class {
+		// The profile has changed since: the recorded class stands, but
+		// which rule gave it is no longer known.
+		return profile.Match{Class: inv.Class}
+	}
+	return m
+}
+
+func lastShimInvocation(db *store.DB, ctxKey string) (store.Invocation, error) {
+	invs, err := db.ListInvocations(ctxKey, "", 50)
+	if err != nil {
+		return store.Invocation{}, err
+	}
+	for _, inv := range invs {
+		if inv.Tool != "ackchyually" {
+			return inv, nil
+		}
+	}
+	return store.Invocation{}, sql.ErrNoRows
+}
+
+func invocationArgv(inv store.Invocation) []string {
+	var argv []string
+	if err := json.Unmarshal([]byte(inv.ArgvJSON), &argv); err != nil || len(argv) == 0 {
+		argv = []string{inv.Tool}
+	}
+	return argv
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/profile"
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+func insertInvocation(t *testing.T, inv store.Invocation) int64 {
+	t.Helper()
+	var id int64
+	if err := store.WithDB(func(db *store.DB) error {
+		if err := db.InsertInvocation(inv); err != nil {
+			return err
+		}
+		invs, err := db.ListInvocations(inv.ContextKey, inv.Tool, 1)
+		if err != nil || len(invs) == 0 {
+			return err
+		}
+		id = invs[0].ID
+		return nil
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	return id
+}
+
+func TestWhy_ExplainsUsageFailure(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	seedInvocation(t, ctxKey, "git", []string{"git", "log", "--pretty=oneline"}, time.Now().Add(-time.Hour), 0)
+	seedInvocation(t, ctxKey, "git", []string{"git", "status"}, time.Now().Add(-time.Hour), 0)
+	seedInvocation(t, ctxKey, "git", []string{"git", "log", "--author=<redacted>"}, time.Now().Add(-time.Hour), 0)
+	insertInvocation(t, store.Invocation{
+		At: time.Now(), ContextKey: ctxKey, Tool: "git", ArgvJSON: `["git","log","--prety=oneline"]`,
+		ExitCode: 129, Mode: "pipes", StderrTail: "error: unknown option `prety=oneline'\nusage: git log", Class: profile.ClassUsage,
+	})
+
+	code, out, _ := captureStdoutStderr(t, func() int { return whyCmd([]string{"--last"}) })
+	if code != 0 {
+		t.Fatalf("why returned %d", code)
+	}
+	for _, want := range []string{
+		"command:  git log --prety=oneline",
+		"class: usage",
+		"rule:  usage-banner",
+		"line:  usage: git log",
+		"score=3021  match=3 prefix=2 uses=1  git log --pretty=oneline",
+		"skip git status: no argument tokens in common",
+		"contains <redacted>",
+		"winner: git log --pretty=oneline",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("why output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWhy_ByIDExplainsNonUsage(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	id := insertInvocation(t, store.Invocation{
+		At: time.Now(), ContextKey: ctxKey, Tool: "curl", ArgvJSON: `["curl","https://example.invalid"]`,
+		ExitCode: 6, Mode: "pipes", StderrTail: "curl: (6) Could not resolve host: example.invalid", Class: profile.ClassNetwork,
+	})
+	insertInvocation(t, store.Invocation{At: time.Now(), ContextKey: ctxKey, Tool: "git", ArgvJSON: `["git","status"]`, Mode: "pipes", Class: profile.ClassOK})
+
+	code, out, _ := captureStdoutStderr(t, func() int { return whyCmd([]string{strconv.FormatInt(id, 10)}) })
+	if code != 0 {
+		t.Fatalf("why returned %d", code)
+	}
+	if !strings.Contains(out, "class: network") || !strings.Contains(out, "only usage failures get suggestions") {
+		t.Fatalf("unexpected why output:\n%s", out)
+	}
+}
+
+func TestWhy_KeepsRecordedInterruptAndFullScreen(t *testing.T) {
+	ctxKey := setTempHomeAndCWD(t)
+	// The tails alone read as a usage error; the record says otherwise.
+	interrupted := insertInvocation(t, store.Invocation{
+		At: time.Now(), ContextKey: ctxKey, Tool: "git", ArgvJSON: `["git","log","--prety"]`,
+		ExitCode: 130, Mode: "pipes", StderrTail: "usage: git log", Class: profile.ClassInterrupted, Signal: "SIGINT",
+	})
+	fullScreen := insertInvocation(t, store.Invocation{
+		At: time.Now(), ContextKey: ctxKey, Tool: "less", ArgvJSON: `["less","--bogus"]`,
+		ExitCode: 1, Mode: "pty", CombinedTail: "usage: less", Class: profile.ClassTUI,
+	})
+
+	for id, want := range map[int64]string{
+		interrupted: "class: interrupted\n  rule:  signal\n  line:  SIGINT",
+		fullScreen:  "class: tui\n  rule:  alternate-screen",
+	} {
+		code, out, _ := captureStdoutStderr(t, func() int { return whyCmd([]string{strconv.FormatInt(id, 10)}) })
+		if code != 0 || !strings.Contains(out, want) || strings.Contains(out, "winner:") {
+			t.Fatalf("why %d = %d, want %q and no suggestion:\n%s", id, code, want, out)
+		}
+	}
+}
+
+func TestWhy_Errors(t *testing.T) {
+	setTempHomeAndCWD(t)
+
+	if code, _, errOut := captureStdoutStderr(t, func() int { return whyCmd(nil) }); code != 1 || !strings.Contains(errOut, "no invocations recorded") {
+		t.Fatalf("why (empty) = %d, stderr:\n%s", code, errOut)
+	}
+	if code, _, errOut := captureStdoutStderr(t, func() int { return whyCmd([]string{"999"}) }); code != 1 || !strings.Contains(errOut, "no invocation with id 999") {
+		t.Fatalf("why 999 = %d, stderr:\n%s", code, errOut)
+	}
+	if code, _, _ := captureStdoutStderr(t, func() int { return whyCmd([]string{"nope"}) }); code != 2 {
+		t.Fatalf("why nope = %d, want 2", code)
+	}
+}
+
+func TestWhy_NeverRunsTheTool(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	ctxKey := setTempHomeAndCWD(t)
+	tmp := t.TempDir()
+	writeExec(t, tmp, "kubectl", kubeTool, "")
+	exe := filepath.Join(tmp, "kubectl")
+	completeLog := filepath.Join(tmp, "complete.log")
+	t.Setenv("COMPLETE_LOG", completeLog)
+
+	var toolID int64
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		toolID, err = db.UpsertTool(store.ToolIdentity{ExePath: exe, SHA256: "kubesha"})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	insertInvocation(t, store.Invocation{
+		At: time.Now(), ContextKey: ctxKey, Tool: "kubectl", ExePath: exe, ToolID: toolID,
+		ArgvJSON: `["kubectl","config","viwe"]`, ExitCode: 1, Mode: "pipes",
+		StderrTail: "error: unknown command or flag", Class: profile.ClassUsage,
+	})
+
+	if code, _, _ := captureStdoutStderr(t, func() int { return whyCmd(nil) }); code != 0 {
+		t.Fatalf("why returned %d", code)
+	}
+	if _, err := os.Stat(completeLog); !os.IsNotExist(err) {
+		t.Fatalf("why ran kubectl __complete (stat err %v); it should only read the cache", err)
+	}
+}
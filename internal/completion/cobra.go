@@ -0,0 +1,123 @@
+// Package completion asks tools for their own shell completions. Today that is
+// cobra's hidden `__complete` command, which lists the valid subcommands and
+// flags for a partial argv (gh, kubectl, helm, hugo, ...).
+package completion
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/execx"
+)
+
+// Timeout bounds each `__complete` query. Completions that hit the network
+// (e.g. listing pull requests) are given up on rather than waited for.
+const Timeout = time.Second
+
+// cobraMarkers are strings every cobra binary embeds (the hidden completion
+// commands and its completion-script directives). Detecting cobra this way
+// means `__complete` is never passed to a tool that would treat it as a file,
+// host or target name.
+var cobraMarkers = [][]byte{[]byte("__completeNoDesc"), []byte("ShellCompDirective")}
+
+// IsCobra reports whether exe contains cobra's completion markers.
+func IsCobra(exe string) bool {
+	f, err := os.Open(exe)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	found := make([]bool, len(cobraMarkers))
+	overlap := 0
+	for _, m := range cobraMarkers {
+		if len(m) > overlap {
+			overlap = len(m)
+		}
+	}
+	buf := make([]byte, 0, 1<<20)
+	chunk := make([]byte, 1<<20)
+	for {
+		n, err := f.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		all := true
+		for i, m := range cobraMarkers {
+			if !found[i] && bytes.Contains(buf, m) {
+				found[i] = true
+			}
+			all = all && found[i]
+		}
+		if all {
+			return true
+		}
+		if err != nil {
+			return false
+		}
+		// Keep a tail so markers spanning two reads are still found.
+		if len(buf) > overlap {
+			buf = append(buf[:0], buf[len(buf)-overlap:]...)
+		}
+	}
+}
+
+// Query runs `exe __complete args...` (the last arg is the word being
+// completed: "" for subcommands, "-" for flags) and returns the candidate
+// words without descriptions. ok is false if the tool did not answer with the
+// cobra protocol in time.
+func Query(exe string, args []string) (words []string, ok bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, exe, append([]string{"__complete"}, args...)...)
+	cmd.Stdin = strings.NewReader("")
+	cmd.Stderr = io.Discard
+	// Without the shims on PATH: what the tool runs in turn isn't recorded.
+	cmd.Env = execx.SanitizedEnv()
+	// A grandchild holding stdout open mustn't keep us past Timeout.
+	cmd.WaitDelay = 100 * time.Millisecond
+	out, err := cmd.Output()
+	if ctx.Err() != nil || err != nil {
+		return nil, false
+	}
+	return Parse(string(out))
+}
+
+// Parse reads cobra's completion output: one "word<TAB>description" per line
+// and a final ":<directive>" line. Directive bit 1 (ShellCompDirectiveError)
+// means no completions.
+func Parse(out string) (words []string, ok bool) {
+	var lines []string
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		if l := strings.TrimRight(sc.Text(), "\r"); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, false
+	}
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, ":") {
+		return nil, false
+	}
+	directive, err := strconv.Atoi(last[1:])
+	if err != nil {
+		return nil, false
+	}
+	if directive&1 != 0 {
+		return nil, true
+	}
+	for _, l := range lines[:len(lines)-1] {
+		w, _, _ := strings.Cut(l, "\t")
+		if w = strings.TrimSpace(w); w != "" {
+			words = append(words, w)
+		}
+	}
+	return words, true
+}
@@ -0,0 +1,134 @@
+package completion
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/execx"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		out    string
+		want   string
+		wantOK bool
+	}{
+		{"subcommands", "completion\tGenerate completion\nhelp\tHelp about any command\nstatus\tShow status\n:4\n", "completion help status", true},
+		{"flags", "--pretty\toutput format\n-h\thelp\n:4\n", "--pretty -h", true},
+		{"no descriptions", "view\nuse-context\n:4\n", "view use-context", true},
+		{"error directive", ":1\n", "", true},
+		{"not cobra", "error: no such file __complete\n", "", false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			words, ok := Parse(tt.out)
+			if ok != tt.wantOK || strings.Join(words, " ") != tt.want {
+				t.Fatalf("Parse = %q, %v; want %q, %v", words, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestIsCobra(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, b []byte) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, b, 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		return p
+	}
+
+	if !IsCobra(write("small", []byte("x __completeNoDesc y ShellCompDirectiveError z"))) {
+		t.Fatal("IsCobra(small) = false")
+	}
+	// A marker straddling the 1 MiB read boundary.
+	big := make([]byte, 1<<20-5)
+	big = append(big, []byte("__completeNoDesc ... ShellCompDirective")...)
+	if !IsCobra(write("big", big)) {
+		t.Fatal("IsCobra(marker across reads) = false")
+	}
+	if IsCobra(write("other", []byte("__complete only"))) {
+		t.Fatal("IsCobra(one marker) = true")
+	}
+	if IsCobra(filepath.Join(dir, "missing")) {
+		t.Fatal("IsCobra(missing) = true")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	exe := filepath.Join(t.TempDir(), "tool")
+	script := "#!/bin/sh\n[ \"$1\" = __complete ] || exit 2\nshift\necho \"args:$*\" >&2\nprintf 'status\\tShow status\\n:4\\n'\n"
+	if err := os.WriteFile(exe, []byte(script), 0o755); err != nil { //nolint:gosec
+		t.Fatalf("write: %v", err)
+	}
+	words, ok := Query(exe, []string{""})
+	if !ok || strings.Join(words, " ") != "status" {
+		t.Fatalf("Query = %q, %v", words, ok)
+	}
+
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil { //nolint:gosec
+		t.Fatalf("write: %v", err)
+	}
+	if _, ok := Query(exe, []string{""}); ok {
+		t.Fatal("Query(failing tool) ok = true")
+	}
+}
+
+func TestQuery_RunsWithoutShimsOnPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	shimDir := execx.ShimDir()
+	if err := os.MkdirAll(shimDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", shimDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// A "shim" for git that records being run, and a tool whose completion
+	// calls git the way gh does.
+	recorded := filepath.Join(t.TempDir(), "recorded")
+	//nolint:gosec
+	if err := os.WriteFile(filepath.Join(shimDir, "git"), []byte("#!/bin/sh\ntouch "+recorded+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	exe := filepath.Join(t.TempDir(), "tool")
+	//nolint:gosec
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\ngit remote >/dev/null 2>&1\nprintf 'status\\tShow status\\n:4\\n'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if words, ok := Query(exe, []string{""}); !ok || strings.Join(words, " ") != "status" {
+		t.Fatalf("Query = %q, %v", words, ok)
+	}
+	if _, err := os.Stat(recorded); err == nil {
+		t.Fatal("Query ran the tool with the shim dir on PATH: its git call went through the shim")
+	}
+}
+
+func TestQuery_DoesNotWaitForGrandchildren(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	// Answers, then leaves a background process holding stdout open.
+	exe := filepath.Join(t.TempDir(), "tool")
+	//nolint:gosec
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\nprintf 'status\\n:4\\n'\nsleep 30 &\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	Query(exe, []string{""})
+	if d := time.Since(start); d > Timeout+time.Second {
+		t.Fatalf("Query took %v; want it bounded by Timeout", d)
+	}
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config is the optional user configuration, read from
+// ~/.local/share/ackchyually/config.toml (or $ACKCHYUALLY_CONFIG).
+//
+// A missing file is not an error: every field has a usable zero value.
+type Config struct {
+	Privacy  Privacy  `toml:"privacy"`
+	Suggest  Suggest  `toml:"suggest"`
+	AutoExec AutoExec `toml:"auto_exec"`
+	Lineage  Lineage  `toml:"lineage"`
+	// Passthrough lists tools whose shims exec the real tool directly.
+	Passthrough Passthrough `toml:"passthrough"`
+}
+
+// Privacy lists contexts where shims pass through transparently but record
+// nothing and never print suggestions.
+type Privacy struct {
+	// IgnoreContexts are exact context keys (e.g. "git:/Users/me/clients/acme").
+	IgnoreContexts []string `toml:"ignore_contexts"`
+	// IgnorePaths are path globs; "~" expands to $HOME and "**" matches any
+	// number of path segments (e.g. "~/clients/**").
+	IgnorePaths []string `toml:"ignore_paths"`
+}
+
+// Suggest tunes the suggestions printed after a usage failure.
+type Suggest struct {
+	// Count is how many ranked commands to print (default 3).
+	Count int `toml:"count"`
+	// Picker shows an inline chooser instead of a printed list when the shim
+	// runs on a terminal (never for agents).
+	Picker bool `toml:"picker"`
+	// NoHelpText stops ackchyually from asking tools for their flags and
+	// subcommands (`<tool> --help`, cobra's `__complete`; once per tool
+	// version) to fix typos that history can't.
+	NoHelpText bool `toml:"no_help_text"`
+}
+
+// Passthrough makes the shims of Tools replace themselves with the real tool
+// (as ACKCHYUALLY_OFF=1 does for every tool): nothing is recorded or printed
+// and no PTY or extra process is involved.
+type Passthrough struct {
+	Tools []string `toml:"tools"`
+}
+
+// Lineage records the tools a shimmed tool runs in turn (gh running git,
+// make running go) as its children. Off by default.
+type Lineage struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// DefaultSuggestCount is used when suggest.count is unset or not positive.
+const DefaultSuggestCount = 3
+
+func (s Suggest) N() int {
+	if s.Count <= 0 {
+		return DefaultSuggestCount
+	}
+	return s.Count
+}
+
+// AutoExec tunes ACKCHYUALLY_AUTO_EXEC=confirm.
+type AutoExec struct {
+	// ConfirmTimeout is how long to wait for an answer (default 10s).
+	// No answer means no.
+	ConfirmTimeout time.Duration `toml:"confirm_timeout"`
+	// PromoteAfter is how many acceptances in a row make a correction run
+	// without asking (default 3; negative never promotes).
+	PromoteAfter int `toml:"promote_after"`
+}
+
+const (
+	DefaultConfirmTimeout = 10 * time.Second
+	DefaultPromoteAfter   = 3
+)
+
+func (a AutoExec) Timeout() time.Duration {
+	if a.ConfirmTimeout <= 0 {
+		return DefaultConfirmTimeout
+	}
+	return a.ConfirmTimeout
+}
+
+func (a AutoExec) Promote() int {
+	if a.PromoteAfter == 0 {
+		return DefaultPromoteAfter
+	}
+	return a.PromoteAfter
+}
+
+// Dir is the ackchyually data directory (shared with the DB and shims).
+func Dir() string {
+	return filepath.Join(homeDir(), ".local", "share", "ackchyually")
+}
+
+func Path() string {
+	if p := strings.TrimSpace(os.Getenv("ACKCHYUALLY_CONFIG")); p != "" {
+		return p
+	}
+	return filepath.Join(Dir(), "config.toml")
+}
+
+func Load() (Config, error) {
+	return LoadFile(Path())
+}
+
+func LoadFile(path string) (Config, error) {
+	var c Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Config{}, nil
+		}
+		return Config{}, err
+	}
+	if err := toml.Unmarshal(b, &c); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// ExpandHome replaces a leading "~" with the user's home directory.
+func ExpandHome(p string) string {
+	if p == "~" {
+		return homeDir()
+	}
+	if strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		return filepath.Join(homeDir(), p[2:])
+	}
+	return p
+}
+
+// MatchPath reports whether path matches the glob pattern. Segments are
+// matched with filepath.Match; a "**" segment matches zero or more segments.
+func MatchPath(pattern, path string) bool {
+	pattern = filepath.ToSlash(filepath.Clean(ExpandHome(strings.TrimSpace(pattern))))
+	path = filepath.ToSlash(filepath.Clean(path))
+	if pattern == "" || pattern == "." {
+		return false
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchSegments(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(rest, segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		ok, err := filepath.Match(pat[0], segs[0])
+		if err != nil || !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+func homeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.Getenv("HOME")
+	}
+	if home == "" {
+		home = "."
+	}
+	return home
+}
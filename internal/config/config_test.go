@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadFile_MissingIsEmpty(t *testing.T) {
+	c, err := LoadFile(filepath.Join(t.TempDir(), "nope.toml"))
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(c.Privacy.IgnoreContexts) != 0 || len(c.Privacy.IgnorePaths) != 0 {
+		t.Fatalf("LoadFile(missing) = %+v, want zero config", c)
+	}
+}
+
+func TestLoadFile_ParsesPrivacy(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.toml")
+	data := `
+[privacy]
+ignore_contexts = ["git:/work/acme"]
+ignore_paths = ["~/clients/**"]
+`
+	if err := os.WriteFile(p, []byte(data), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	c, err := LoadFile(p)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(c.Privacy.IgnoreContexts) != 1 || c.Privacy.IgnoreContexts[0] != "git:/work/acme" {
+		t.Fatalf("IgnoreContexts = %v", c.Privacy.IgnoreContexts)
+	}
+	if len(c.Privacy.IgnorePaths) != 1 || c.Privacy.IgnorePaths[0] != "~/clients/**" {
+		t.Fatalf("IgnorePaths = %v", c.Privacy.IgnorePaths)
+	}
+}
+
+func TestLoadFile_InvalidTOML(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(p, []byte("[privacy\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := LoadFile(p); err == nil {
+		t.Fatal("LoadFile: expected error for invalid TOML")
+	}
+}
+
+func TestPath_EnvOverride(t *testing.T) {
+	t.Setenv("ACKCHYUALLY_CONFIG", "/tmp/custom.toml")
+	if got := Path(); got != "/tmp/custom.toml" {
+		t.Fatalf("Path() = %q, want /tmp/custom.toml", got)
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"~/clients/**", filepath.Join(home, "clients"), true},
+		{"~/clients/**", filepath.Join(home, "clients", "acme", "src"), true},
+		{"~/clients/**", filepath.Join(home, "personal"), false},
+		{"~/clients/*", filepath.Join(home, "clients", "acme"), true},
+		{"~/clients/*", filepath.Join(home, "clients", "acme", "src"), false},
+		{"/work/**/secret", "/work/a/b/secret", true},
+		{"/work/**/secret", "/work/a/b/public", false},
+		{"", "/work", false},
+	}
+	for _, tt := range tests {
+		if got := MatchPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSuggest_N(t *testing.T) {
+	if got := (Suggest{}).N(); got != DefaultSuggestCount {
+		t.Fatalf("Suggest{}.N() = %d, want %d", got, DefaultSuggestCount)
+	}
+	if got := (Suggest{Count: 5}).N(); got != 5 {
+		t.Fatalf("Suggest{Count: 5}.N() = %d, want 5", got)
+	}
+}
+
+func TestLoadFile_ParsesAutoExec(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.toml")
+	data := `
+[auto_exec]
+confirm_timeout = "3s"
+promote_after = -1
+`
+	if err := os.WriteFile(p, []byte(data), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	c, err := LoadFile(p)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if got := c.AutoExec.Timeout(); got != 3*time.Second {
+		t.Fatalf("Timeout() = %v, want 3s", got)
+	}
+	if got := c.AutoExec.Promote(); got != -1 {
+		t.Fatalf("Promote() = %d, want -1", got)
+	}
+	if got := (AutoExec{}).Timeout(); got != DefaultConfirmTimeout {
+		t.Fatalf("AutoExec{}.Timeout() = %v", got)
+	}
+	if got := (AutoExec{}).Promote(); got != DefaultPromoteAfter {
+		t.Fatalf("AutoExec{}.Promote() = %d", got)
+	}
+}
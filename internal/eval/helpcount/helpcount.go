@@ -200,6 +200,7 @@ func (r *Runner) runOne(s Scenario, mode Mode) RunResult { //nolint:gocyclo
 		}
 	}
 
+	_ = os.Remove(env.SuggestFile)
 	badOut, badErr := env.RunShim(s.Tool, s.Bad.Args...)
 	steps++
 
@@ -217,7 +218,7 @@ func (r *Runner) runOne(s Scenario, mode Mode) RunResult { //nolint:gocyclo
 		badOK = false
 	}
 
-	suggested, suggestionPrinted := ExtractSuggestion(badOut)
+	suggested, suggestionPrinted := env.Suggestion(badOut)
 
 	finalOut := ""
 	finalExit := 0
@@ -310,6 +311,9 @@ type Env struct {
 	Home    string
 	WorkDir string
 	ShimDir string
+	// SuggestFile receives ackchyually's JSON failure reports
+	// (ACKCHYUALLY_SUGGEST_FILE) for shimmed runs.
+	SuggestFile string
 
 	ackPath    string
 	basePath   string
@@ -345,15 +349,18 @@ func NewEnv(ackPath string) (*Env, error) {
 	directEnv = upsertEnv(directEnv, "PATH", basePath)
 	directEnv = deleteEnv(directEnv, "ACKCHYUALLY_AUTO_EXEC")
 
+	suggestFile := filepath.Join(base, "suggestions.jsonl")
 	shimmedEnv := append([]string{}, directEnv...)
 	shimmedEnv = upsertEnv(shimmedEnv, "PATH", strings.Join([]string{shimDir, basePath}, string(os.PathListSeparator)))
+	shimmedEnv = upsertEnv(shimmedEnv, "ACKCHYUALLY_SUGGEST_FILE", suggestFile)
 
 	return &Env{
-		BaseDir: base,
-		Home:    home,
-		WorkDir: work,
-		ShimDir: shimDir,
-		ackPath: ackPath,
+		BaseDir:     base,
+		Home:        home,
+		WorkDir:     work,
+		ShimDir:     shimDir,
+		SuggestFile: suggestFile,
+		ackPath:     ackPath,
 
 		basePath:   basePath,
 		shimmedEnv: shimmedEnv,
@@ -460,9 +467,40 @@ func isHelpInvocation(argv []string) bool {
 	return false
 }
 
+// Suggestion returns the top suggestion for the last shimmed run: from the
+// JSON report in SuggestFile when one was written, else scraped from output.
+func (e *Env) Suggestion(output string) (string, bool) {
+	if b, err := os.ReadFile(e.SuggestFile); err == nil {
+		return ExtractSuggestionJSON(string(b))
+	}
+	return ExtractSuggestion(output)
+}
+
+// ExtractSuggestionJSON reads the top suggested command from the last report
+// line written via ACKCHYUALLY_SUGGEST_FILE/FD or ACKCHYUALLY_OUTPUT=json.
+func ExtractSuggestionJSON(reports string) (string, bool) {
+	lines := strings.Split(strings.TrimSpace(reports), "\n")
+	var rep struct {
+		Suggestions []struct {
+			Command string `json:"command"`
+		} `json:"suggestions"`
+	}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &rep); err != nil || len(rep.Suggestions) == 0 {
+		return "", false
+	}
+	return rep.Suggestions[0].Command, true
+}
+
+// ExtractSuggestion scrapes the top suggested command from ackchyually's
+// human-readable stderr output, or from the one-line form printed for agents
+// ("ackchyually: suggestion: <cmd>  # reasons").
 func ExtractSuggestion(output string) (string, bool) {
 	lines := strings.Split(output, "\n")
 	for i := 0; i < len(lines); i++ {
+		if _, rest, ok := strings.Cut(lines[i], "ackchyually: suggestion: "); ok {
+			cmd, _, _ := strings.Cut(rest, "  # ")
+			return strings.TrimSpace(cmd), true
+		}
 		if strings.Contains(lines[i], "ackchyually: suggestion") ||
 			strings.Contains(lines[i], "ackchyually: this worked before here:") {
 			for j := i + 1; j < len(lines); j++ {
@@ -110,12 +110,39 @@ func TestExtractSuggestion(t *testing.T) {
 		t.Fatalf("expected placeholder suggestion, got %q ok=%v", cmd, ok)
 	}
 
+	cmd, ok = ExtractSuggestion("ackchyually: suggestion: git log --pretty=%s  # fixes --prety→--pretty\nackchyually: alternative: git log\n")
+	if !ok || cmd != "git log --pretty=%s" {
+		t.Fatalf("expected agent one-line suggestion, got %q ok=%v", cmd, ok)
+	}
+
 	cmd, ok = ExtractSuggestion("no suggestion here")
 	if ok || cmd != "" {
 		t.Fatalf("expected no suggestion, got %q ok=%v", cmd, ok)
 	}
 }
 
+func TestExtractSuggestionJSON(t *testing.T) {
+	t.Parallel()
+
+	reports := `{"version":1,"tool":"git","suggestions":[{"command":"git status"}]}
+{"version":1,"tool":"git","argv":["git","lgo"],"suggestions":[{"command":"git log --oneline","source":"history"},{"command":"git log"}]}
+`
+	cmd, ok := ExtractSuggestionJSON(reports)
+	if !ok || cmd != "git log --oneline" {
+		t.Fatalf("expected last report's top suggestion, got %q ok=%v", cmd, ok)
+	}
+
+	cmd, ok = ExtractSuggestionJSON(`{"version":1,"suggestions":[]}`)
+	if ok || cmd != "" {
+		t.Fatalf("expected no suggestion, got %q ok=%v", cmd, ok)
+	}
+
+	cmd, ok = ExtractSuggestionJSON("not json")
+	if ok || cmd != "" {
+		t.Fatalf("expected no suggestion for garbage, got %q ok=%v", cmd, ok)
+	}
+}
+
 func TestExitCode(t *testing.T) {
 	t.Parallel()
 
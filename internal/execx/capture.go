@@ -0,0 +1,87 @@
+package execx
+
+import "unicode/utf8"
+
+// Sizes of the head and tail kept of each captured stream. Many tools print
+// the line that matters ("unknown option") first and pages of usage or logs
+// after it, so the start of a stream is kept as well as its end.
+const (
+	captureHeadBytes = 8 * 1024
+	captureTailBytes = 64 * 1024
+)
+
+// omittedMarker stands in for the middle of a stream that didn't fit.
+const omittedMarker = "\n[... output omitted ...]\n"
+
+// Capture keeps the first headBytes and the last tailBytes written to it and
+// counts the rest.
+type Capture struct {
+	head    []byte
+	headCap int
+	tail    *Tail
+	total   int64
+}
+
+func NewCapture(headBytes, tailBytes int) *Capture {
+	return &Capture{headCap: headBytes, tail: NewTail(tailBytes)}
+}
+
+func (c *Capture) Write(p []byte) (int, error) {
+	n := len(p)
+	c.total += int64(n)
+	if room := c.headCap - len(c.head); room > 0 {
+		k := min(room, len(p))
+		c.head = append(c.head, p[:k]...)
+		p = p[k:]
+	}
+	if len(p) > 0 {
+		_, _ = c.tail.Write(p)
+	}
+	return n, nil
+}
+
+// Total is the number of bytes written, kept or not.
+func (c *Capture) Total() int64 { return c.total }
+
+// Truncated reports whether bytes between the head and the tail were dropped.
+func (c *Capture) Truncated() bool {
+	return c.total > int64(len(c.head)+c.tail.Len())
+}
+
+// String returns the head and the tail, with omittedMarker between them if
+// anything was dropped.
+func (c *Capture) String() string {
+	if !c.Truncated() {
+		return string(c.head) + c.tail.String()
+	}
+	return trimRuneEnd(string(c.head)) + omittedMarker + trimRuneStart(c.tail.String())
+}
+
+// clip keeps the first headBytes and last tailBytes of s.
+func clip(s string, headBytes, tailBytes int) (string, bool) {
+	if len(s) <= headBytes+tailBytes {
+		return s, false
+	}
+	return trimRuneEnd(s[:headBytes]) + omittedMarker + trimRuneStart(s[len(s)-tailBytes:]), true
+}
+
+// trimRuneStart drops a partial UTF-8 sequence cut off at the start of s.
+func trimRuneStart(s string) string {
+	for len(s) > 0 && !utf8.RuneStart(s[0]) {
+		s = s[1:]
+	}
+	return s
+}
+
+// trimRuneEnd drops a partial UTF-8 sequence cut off at the end of s.
+func trimRuneEnd(s string) string {
+	for i := len(s) - 1; i >= 0 && i >= len(s)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(s[i]) {
+			if !utf8.FullRuneInString(s[i:]) {
+				return s[:i]
+			}
+			break
+		}
+	}
+	return s
+}
@@ -55,6 +55,35 @@ func TestTail_TrimsToCapacity(t *testing.T) {
 	}
 }
 
+func TestTail_WrapsAround(t *testing.T) {
+	tail := NewTail(8)
+	for _, s := range []string{"abc", "defgh", "ij", "klmnopqrstuvwxyz", "12"} {
+		_, _ = tail.Write([]byte(s))
+	}
+	if got, want := tail.String(), "uvwxyz12"; got != want {
+		t.Fatalf("tail=%q, want %q", got, want)
+	}
+}
+
+func TestCapture_KeepsHeadAndTail(t *testing.T) {
+	c := NewCapture(6, 5)
+	_, _ = c.Write([]byte("error:"))
+	_, _ = c.Write([]byte(" unknown option\nusage"))
+	_, _ = c.Write([]byte(" ... done\n"))
+	if got, want := c.String(), "error:"+omittedMarker+"done\n"; got != want {
+		t.Fatalf("capture=%q, want %q", got, want)
+	}
+	if !c.Truncated() || c.Total() != 37 {
+		t.Fatalf("Truncated()=%v Total()=%d, want true 37", c.Truncated(), c.Total())
+	}
+
+	small := NewCapture(6, 5)
+	_, _ = small.Write([]byte("short\n"))
+	if small.Truncated() || small.String() != "short\n" {
+		t.Fatalf("small capture=%q truncated=%v", small.String(), small.Truncated())
+	}
+}
+
 func TestWhichSkippingShims_SkipsShimDir(t *testing.T) {
 	home := t.TempDir()
 	t.Setenv("HOME", home)
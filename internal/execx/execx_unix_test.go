@@ -29,6 +29,48 @@ func TestExitCode(t *testing.T) {
 	}
 }
 
+func TestResultFinish(t *testing.T) {
+	var r Result
+	r.finish(nil, runShellKilledBySignal(t, syscall.SIGKILL), nil)
+	if r.ExitCode != 128+int(syscall.SIGKILL) || r.Signal != "SIGKILL" || r.Interrupted {
+		t.Fatalf("killed child: %+v", r)
+	}
+
+	r = Result{}
+	r.finish(nil, runShellKilledBySignal(t, syscall.SIGINT), syscall.SIGINT)
+	if r.ExitCode != 130 || r.Signal != "SIGINT" || !r.Interrupted {
+		t.Fatalf("forwarded SIGINT, child died from it: %+v", r)
+	}
+
+	r = Result{}
+	r.finish(nil, runShellExitStatus(t, 3), syscall.SIGINT)
+	if r.ExitCode != 3 || r.Signal != "SIGINT" || !r.Interrupted {
+		t.Fatalf("forwarded SIGINT, child handled it and failed: %+v", r)
+	}
+
+	r = Result{}
+	r.finish(nil, nil, syscall.SIGTERM)
+	if r.ExitCode != 0 || r.Signal != "" || r.Interrupted {
+		t.Fatalf("forwarded SIGTERM, child shut down cleanly: %+v", r)
+	}
+}
+
+func TestRun_RecordsResourceUsage(t *testing.T) {
+	res, err := Run("sh", []string{"-c", `i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done; echo done; echo oops >&2`})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.UserCPU+res.SysCPU <= 0 {
+		t.Errorf("expected CPU time to be recorded, got user=%v sys=%v", res.UserCPU, res.SysCPU)
+	}
+	if res.MaxRSS <= 0 {
+		t.Errorf("expected MaxRSS to be recorded, got %d", res.MaxRSS)
+	}
+	if res.StdoutBytes != 5 || res.StderrBytes != 5 {
+		t.Errorf("expected 5 bytes on each stream, got out=%d err=%d", res.StdoutBytes, res.StderrBytes)
+	}
+}
+
 func runShellExitStatus(t *testing.T, code int) error {
 	t.Helper()
 	cmd := exec.CommandContext(context.Background(), "sh", "-c", "exit "+itoa(code)) //nolint:gosec
@@ -4,6 +4,7 @@ package execx
 
 import (
 	"errors"
+	"os"
 	"os/exec"
 	"syscall"
 )
@@ -24,3 +25,14 @@ func exitCode(err error) int {
 	}
 	return 1
 }
+
+// exitSignal returns the signal that killed the process, if any.
+func exitSignal(err error) os.Signal {
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return ws.Signal()
+		}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ package execx
 
 import (
 	"errors"
+	"os"
 	"os/exec"
 )
 
@@ -17,3 +18,5 @@ func exitCode(err error) int {
 	}
 	return 1
 }
+
+func exitSignal(error) os.Signal { return nil }
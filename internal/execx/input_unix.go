@@ -0,0 +1,79 @@
+//go:build !windows
+
+package execx
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// WaitReadable waits up to timeout for f to have input, so callers can read
+// without blocking past a deadline.
+func WaitReadable(f *os.File, timeout time.Duration) bool {
+	fds := []unix.PollFd{{Fd: int32(f.Fd()), Events: unix.POLLIN}}
+	deadline := time.Now().Add(timeout)
+	for {
+		ms := int(time.Until(deadline) / time.Millisecond)
+		if ms < 0 {
+			ms = 0
+		}
+		n, err := unix.Poll(fds, ms)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil || n == 0 {
+			return false
+		}
+		return fds[0].Revents&(unix.POLLIN|unix.POLLHUP|unix.POLLERR) != 0
+	}
+}
+
+// startInputCopy forwards in to out until the returned stop func is called.
+// stop returns only once forwarding has ended, so nothing typed after the
+// child exits is swallowed: the suggestion prompt (or the user's shell) reads
+// the terminal next. A pipe wakes the poll immediately on stop.
+func startInputCopy(out io.Writer, in *os.File) (stop func()) {
+	wakeR, wakeW, err := os.Pipe()
+	if err != nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer wakeR.Close()
+		fds := []unix.PollFd{
+			{Fd: int32(in.Fd()), Events: unix.POLLIN},
+			{Fd: int32(wakeR.Fd()), Events: unix.POLLIN},
+		}
+		buf := make([]byte, 32*1024)
+		for {
+			_, err := unix.Poll(fds, -1)
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			if err != nil || fds[1].Revents != 0 {
+				return
+			}
+			if fds[0].Revents&(unix.POLLIN|unix.POLLHUP|unix.POLLERR) == 0 {
+				continue
+			}
+			m, err := in.Read(buf)
+			if m > 0 {
+				if _, werr := out.Write(buf[:m]); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return func() {
+		_ = wakeW.Close()
+		<-done
+	}
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package execx
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// WaitReadable waits up to timeout for f to have input, so callers can read
+// without blocking past a deadline.
+func WaitReadable(f *os.File, timeout time.Duration) bool {
+	ev, err := windows.WaitForSingleObject(windows.Handle(f.Fd()), uint32(timeout/time.Millisecond))
+	return err == nil && ev == windows.WAIT_OBJECT_0
+}
@@ -0,0 +1,119 @@
+//go:build !windows
+
+package execx
+
+import (
+	"bytes"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// rawTerminal tracks whether our terminal is in raw mode, so suspending can
+// hand it back in its original state and resuming can take it again.
+type rawTerminal struct {
+	mu      sync.Mutex
+	f       *os.File
+	restore func()
+}
+
+func (t *rawTerminal) makeRaw() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.restore != nil {
+		return
+	}
+	if restore, err := MakeRaw(t.f); err == nil {
+		t.restore = restore
+	}
+}
+
+func (t *rawTerminal) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.restore != nil {
+		t.restore()
+		t.restore = nil
+	}
+}
+
+// resumeGrace bounds the wait for SIGCONT after stopping ourselves. The kernel
+// discards SIGTSTP for a process group no shell is managing (an orphaned
+// one); in that case nobody will ever send SIGCONT, so the child is simply
+// continued again.
+const resumeGrace = 200 * time.Millisecond
+
+// jobControl carries keyboard input to the child's PTY and makes Ctrl-Z
+// suspend the job as it would without the shim.
+//
+// Our terminal is raw, so the kernel never turns Ctrl-Z into SIGTSTP for us,
+// and the child leads its own session, where the kernel discards SIGTSTP (the
+// session has no shell to resume it). So the suspend key is acted on here: the
+// child is stopped, the terminal handed back in its original mode and our own
+// process group stopped, so the shell sees the job as suspended. On `fg` we
+// re-enter raw mode, pick up any resize made in the meantime and continue the
+// child. Children that read keys raw (editors with their own Ctrl-Z binding)
+// get the key as before.
+type jobControl struct {
+	ptmx   *os.File
+	pid    int
+	tty    *rawTerminal
+	resize func()
+}
+
+func (j *jobControl) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := -1
+		if key, ok := suspendKey(j.ptmx); ok {
+			i = bytes.IndexByte(p, key)
+		}
+		if i < 0 {
+			if _, err := j.ptmx.Write(p); err != nil {
+				return 0, err
+			}
+			break
+		}
+		if _, err := j.ptmx.Write(p[:i]); err != nil {
+			return 0, err
+		}
+		j.suspend()
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+func (j *jobControl) suspend() {
+	_ = unix.Kill(-j.pid, syscall.SIGSTOP)
+	j.tty.reset()
+
+	cont := make(chan os.Signal, 1)
+	signal.Notify(cont, syscall.SIGCONT)
+	_ = unix.Kill(0, syscall.SIGTSTP)
+	select {
+	case <-cont:
+	case <-time.After(resumeGrace):
+	}
+	signal.Stop(cont)
+
+	j.tty.makeRaw()
+	j.resize()
+	_ = unix.Kill(-j.pid, syscall.SIGCONT)
+	// Full-screen tools redraw on a size change; the screen now shows the shell.
+	_ = unix.Kill(-j.pid, syscall.SIGWINCH)
+}
+
+// suspendKey is the child terminal's suspend character (`stty susp`), if its
+// line discipline would act on it.
+func suspendKey(ptmx *os.File) (byte, bool) {
+	t, err := unix.IoctlGetTermios(int(ptmx.Fd()), ioctlReadTermios)
+	if err != nil || t.Lflag&unix.ISIG == 0 {
+		return 0, false
+	}
+	key := t.Cc[unix.VSUSP]
+	return key, key != 0 && key != 0xff // _POSIX_VDISABLE
+}
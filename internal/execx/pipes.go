@@ -7,24 +7,35 @@ import (
 	"os/exec"
 )
 
-func runPipes(exe string, args []string) (Result, error) {
+func runPipes(exe string, args, env []string) (Result, error) {
 	cmd := exec.CommandContext(context.Background(), exe, args...)
-	cmd.Env = SanitizedEnv()
+	cmd.Env = env
 	cmd.Stdin = os.Stdin
 
-	outTail := NewTail(64 * 1024)
-	errTail := NewTail(64 * 1024)
+	outTail := NewCapture(captureHeadBytes, captureTailBytes)
+	errTail := NewCapture(captureHeadBytes, captureTailBytes)
 
 	cmd.Stdout = io.MultiWriter(os.Stdout, outTail)
 	cmd.Stderr = io.MultiWriter(os.Stderr, errTail)
 
-	err := cmd.Run()
-	code := exitCode(err)
+	group := ownProcessGroup()
+	if group {
+		setProcessGroup(cmd)
+	}
+	if err := cmd.Start(); err != nil {
+		return Result{ExitCode: exitCode(err), Mode: "pipes"}, err
+	}
+	stopSignals := forwardSignals(cmd, group)
+	err := cmd.Wait()
 
-	return Result{
-		ExitCode:   code,
-		Mode:       "pipes",
-		StdoutTail: outTail.String(),
-		StderrTail: errTail.String(),
-	}, err
+	res := Result{
+		Mode:        "pipes",
+		StdoutTail:  outTail.String(),
+		StderrTail:  errTail.String(),
+		StdoutBytes: outTail.Total(),
+		StderrBytes: errTail.Total(),
+		Truncated:   outTail.Truncated() || errTail.Truncated(),
+	}
+	res.finish(cmd.ProcessState, err, stopSignals())
+	return res, err
 }
@@ -7,7 +7,7 @@ import (
 
 func TestRunPipes_Success(t *testing.T) {
 	// Simple echo command
-	res, err := runPipes("echo", []string{"hello"})
+	res, err := runPipes("echo", []string{"hello"}, SanitizedEnv())
 	if err != nil {
 		t.Fatalf("runPipes failed: %v", err)
 	}
@@ -25,7 +25,7 @@ func TestRunPipes_Success(t *testing.T) {
 func TestRunPipes_Failure(t *testing.T) {
 	// Command that exits with 1
 	// We use 'sh -c exit 1' to ensure portability (mostly)
-	res, err := runPipes("sh", []string{"-c", "exit 1"})
+	res, err := runPipes("sh", []string{"-c", "exit 1"}, SanitizedEnv())
 	if err == nil {
 		// exec.Command.Run() returns an error if the command exits non-zero
 		// but our wrapper returns it alongside the result.
@@ -1,6 +1,7 @@
 package execx
 
 import (
+	"io"
 	"os"
 	"runtime"
 	"strings"
@@ -34,18 +35,127 @@ func TestRun_UsesPTYWhenTTY(t *testing.T) {
 		_ = ptmx.Close()
 	})
 
-	res, err := Run("sh", []string{"-c", "echo hi"})
+	res, err := Run("sh", []string{"-c", "echo hi; [ -t 2 ] && echo oops >&2"})
 	if err != nil {
 		t.Fatalf("Run: %v (res=%#v)", err, res)
 	}
-	if res.Mode != "pty" {
-		t.Fatalf("expected res.Mode=pty, got %q", res.Mode)
+	if res.Mode != "pty:in,out,err" {
+		t.Fatalf("expected res.Mode=pty:in,out,err, got %q", res.Mode)
 	}
 	if res.ExitCode != 0 {
 		t.Fatalf("expected ExitCode=0, got %d", res.ExitCode)
 	}
-	if !strings.Contains(res.CombinedTail, "hi") {
-		t.Fatalf("expected CombinedTail to contain output, got %q", res.CombinedTail)
+	if !strings.Contains(res.CombinedTail, "hi") || !strings.Contains(res.CombinedTail, "oops") {
+		t.Fatalf("expected CombinedTail to contain both streams, got %q", res.CombinedTail)
+	}
+	if !strings.Contains(res.StdoutTail, "hi") || strings.Contains(res.StdoutTail, "oops") {
+		t.Fatalf("expected StdoutTail to hold only stdout, got %q", res.StdoutTail)
+	}
+	if !strings.Contains(res.StderrTail, "oops") || strings.Contains(res.StderrTail, "hi") {
+		t.Fatalf("expected StderrTail to hold only stderr (on a terminal), got %q", res.StderrTail)
+	}
+}
+
+func TestRun_PipedStdinKeepsOutputOnPTY(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no windows PTY support")
+	}
+
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Fatalf("pty.Open: %v", err)
+	}
+	go func() { _, _ = io.Copy(io.Discard, ptmx) }()
+	inR, inW, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	_, _ = inW.WriteString("payload\n")
+	_ = inW.Close()
+
+	oldIn, oldOut, oldErr := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = inR, tty, tty
+	t.Cleanup(func() {
+		os.Stdin, os.Stdout, os.Stderr = oldIn, oldOut, oldErr
+		_ = inR.Close()
+		_ = tty.Close()
+		_ = ptmx.Close()
+	})
+
+	res, err := Run("sh", []string{"-c", `read x; echo "got=$x"; [ -t 0 ] || echo in-pipe; [ -t 1 ] && echo out-tty`})
+	if err != nil {
+		t.Fatalf("Run: %v (res=%#v)", err, res)
+	}
+	if res.Mode != "pty:out,err pipe:in" {
+		t.Fatalf("expected res.Mode=%q, got %q", "pty:out,err pipe:in", res.Mode)
+	}
+	for _, want := range []string{"got=payload", "in-pipe", "out-tty"} {
+		if !strings.Contains(res.StdoutTail, want) {
+			t.Fatalf("expected StdoutTail to contain %q, got %q", want, res.StdoutTail)
+		}
+	}
+}
+
+func TestRun_PipedStdoutIsCapturedAndPassedOn(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no windows PTY support")
+	}
+
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Fatalf("pty.Open: %v", err)
+	}
+	go func() { _, _ = io.Copy(io.Discard, ptmx) }()
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	piped := make(chan string, 1)
+	go func() {
+		b, _ := io.ReadAll(outR)
+		piped <- string(b)
+	}()
+
+	oldIn, oldOut, oldErr := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = tty, outW, tty
+	t.Cleanup(func() {
+		os.Stdin, os.Stdout, os.Stderr = oldIn, oldOut, oldErr
+		_ = outR.Close()
+		_ = tty.Close()
+		_ = ptmx.Close()
+	})
+
+	res, err := Run("sh", []string{"-c", `[ -t 1 ] || echo out-pipe; [ -t 0 ] && [ -t 2 ] && echo tty-err >&2`})
+	_ = outW.Close()
+	if err != nil {
+		t.Fatalf("Run: %v (res=%#v)", err, res)
+	}
+	if res.Mode != "pty:in,err pipe:out" {
+		t.Fatalf("expected res.Mode=%q, got %q", "pty:in,err pipe:out", res.Mode)
+	}
+	if got := <-piped; got != "out-pipe\n" {
+		t.Fatalf("expected the pipe to receive stdout verbatim, got %q", got)
+	}
+	if !strings.Contains(res.StdoutTail, "out-pipe") || !strings.Contains(res.StderrTail, "tty-err") {
+		t.Fatalf("expected tails to capture both streams, got out=%q err=%q", res.StdoutTail, res.StderrTail)
+	}
+}
+
+func TestStreamsMode(t *testing.T) {
+	cases := []struct {
+		s    streams
+		want string
+	}{
+		{streams{}, "pipes"},
+		{streams{in: true, out: true, err: true}, "pty:in,out,err"},
+		{streams{out: true, err: true}, "pty:out,err pipe:in"},
+		{streams{in: true, err: true}, "pty:in,err pipe:out"},
+		{streams{err: true}, "pty:err pipe:in,out"},
+	}
+	for _, c := range cases {
+		if got := c.s.mode(); got != c.want {
+			t.Errorf("%+v.mode() = %q, want %q", c.s, got, c.want)
+		}
 	}
 }
 
@@ -54,7 +164,7 @@ func TestRunPTY_StartError(t *testing.T) {
 		t.Skip("no windows PTY support")
 	}
 	// Missing executable should cause pty.Start to fail
-	res, err := runPTY("missingtool_xyz", []string{})
+	res, err := runPTY("missingtool_xyz", []string{}, SanitizedEnv(), streams{in: true, out: true, err: true})
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -71,7 +181,7 @@ func TestRun_NoTTY(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Run: %v", err)
 	}
-	if res.Mode == "pty" {
+	if strings.HasPrefix(res.Mode, "pty") {
 		// If it runs as PTY, then we are in a TTY environment.
 		// That's fine, but we wanted to test the non-PTY path of Run.
 		// To force non-PTY, we can pipe stdin?
@@ -8,67 +8,250 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	"github.com/creack/pty"
-	"golang.org/x/term"
 )
 
-func runPTY(exe string, args []string) (Result, error) {
+// ptySupported: any stream can get a terminal of its own.
+func ptySupported(streams) bool { return true }
+
+// runPTY runs the child with a PTY for each of the streams in s and plain
+// pipes for the rest. stdin and stdout share one PTY (as a terminal's input
+// and output do); stderr gets a second one, so it stays a TTY to the child
+// (colors, progress bars) but is captured apart from stdout.
+func runPTY(exe string, args, env []string, s streams) (Result, error) {
 	cmd := exec.CommandContext(context.Background(), exe, args...)
-	cmd.Env = SanitizedEnv()
+	cmd.Env = env
+
+	// What came through a PTY is captured through a Screen, as it was seen;
+	// piped streams are kept verbatim.
+	var outMu sync.Mutex
+	outTail, errTail := capture(s.out), capture(s.err)
+	combined := NewScreen(captureHeadBytes, captureTailBytes)
+
+	var ptmx, errPtmx *os.File
+	closeMasters := func() {
+		for _, f := range []*os.File{ptmx, errPtmx} {
+			if f != nil {
+				_ = f.Close()
+			}
+		}
+	}
+	var slaves []*os.File
+	closeSlaves := func() {
+		for _, f := range slaves {
+			_ = f.Close()
+		}
+	}
 
-	ptmx, err := pty.Start(cmd)
+	if s.err {
+		// Without a second PTY stderr is piped rather than mixed into stdout.
+		if m, t, err := pty.Open(); err == nil {
+			errPtmx = m
+			slaves = append(slaves, t)
+			cmd.Stderr = t
+		} else {
+			s.err = false
+			errTail = capture(false)
+		}
+	}
+	if !s.err {
+		cmd.Stderr = streamWriter{mu: &outMu, dst: os.Stderr, tail: errTail, combined: combined}
+	}
+	if s.in || s.out {
+		m, t, err := pty.Open()
+		if err != nil {
+			closeMasters()
+			closeSlaves()
+			return Result{ExitCode: 1, Mode: s.mode()}, err
+		}
+		ptmx = m
+		slaves = append(slaves, t)
+		if s.in {
+			cmd.Stdin = t
+		}
+		if s.out {
+			cmd.Stdout = t
+		}
+	}
+	if !s.in {
+		cmd.Stdin = os.Stdin
+	}
+	if !s.out {
+		cmd.Stdout = streamWriter{mu: &outMu, dst: os.Stdout, tail: outTail, combined: combined}
+	}
+	if !s.any() {
+		// Every PTY failed to open.
+		return runPipes(exe, args, env)
+	}
+
+	// The child leads a session whose controlling terminal is its first
+	// stream on a PTY (Ctty is a descriptor number in the child).
+	ctty := 0
+	switch {
+	case s.in:
+	case s.out:
+		ctty = 1
+	default:
+		ctty = 2
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: ctty}
+
+	err := cmd.Start()
+	closeSlaves()
 	if err != nil {
-		return Result{ExitCode: 1, Mode: "pty"}, err
+		closeMasters()
+		return Result{ExitCode: 1, Mode: s.mode()}, err
 	}
-	defer func() { _ = ptmx.Close() }()
+	defer closeMasters()
 
-	if oldState, err := term.MakeRaw(int(os.Stdin.Fd())); err == nil {
-		defer func() {
-			if err := term.Restore(int(os.Stdin.Fd()), oldState); err != nil {
+	// Window size comes from whichever of our streams is a terminal.
+	sizeFrom := os.Stderr
+	switch {
+	case s.in:
+		sizeFrom = os.Stdin
+	case s.out:
+		sizeFrom = os.Stdout
+	}
+	inheritSize := func() {
+		for _, m := range []*os.File{ptmx, errPtmx} {
+			if m == nil {
+				continue
+			}
+			if err := pty.InheritSize(sizeFrom, m); err != nil {
 				_ = err // best-effort
 			}
-		}()
-	}
-
-	if err := pty.InheritSize(os.Stdin, ptmx); err != nil {
-		_ = err // best-effort
+		}
+		if rows, _, err := pty.Getsize(sizeFrom); err == nil {
+			outMu.Lock()
+			for _, c := range []tailWriter{outTail, errTail, combined} {
+				if sc, ok := c.(*Screen); ok {
+					sc.SetRows(rows)
+				}
+			}
+			outMu.Unlock()
+		}
 	}
+	inheritSize()
 
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGWINCH)
 	defer signal.Stop(ch)
 	go func() {
 		for range ch {
-			if err := pty.InheritSize(os.Stdin, ptmx); err != nil {
-				_ = err // best-effort
-			}
+			inheritSize()
 		}
 	}()
 
-	combined := NewTail(64 * 1024)
-
 	outputDone := make(chan struct{})
-	go func() {
-		if _, err := io.Copy(io.MultiWriter(os.Stdout, combined), ptmx); err != nil {
-			_ = err // best-effort
+	if ptmx != nil {
+		// With stdout piped the PTY still carries the echo of typed input and
+		// anything written to /dev/tty (password prompts); that goes to our
+		// terminal, not into the pipe.
+		w := streamWriter{mu: &outMu, dst: os.Stdout, tail: outTail, combined: combined}
+		if !s.out {
+			dst, closeDst := terminalWriter()
+			defer closeDst()
+			w = streamWriter{mu: &outMu, dst: dst, combined: combined}
 		}
+		go func() {
+			if _, err := io.Copy(w, ptmx); err != nil {
+				_ = err // best-effort
+			}
+			close(outputDone)
+		}()
+	} else {
 		close(outputDone)
-	}()
-	go func() {
-		if _, err := io.Copy(ptmx, os.Stdin); err != nil {
-			_ = err // best-effort
-		}
-	}()
+	}
+	errDone := make(chan struct{})
+	if errPtmx != nil {
+		go func() {
+			if _, err := io.Copy(streamWriter{mu: &outMu, dst: os.Stderr, tail: errTail, combined: combined}, errPtmx); err != nil {
+				_ = err // best-effort
+			}
+			close(errDone)
+		}()
+	} else {
+		close(errDone)
+	}
+
+	stopInput := func() {}
+	if s.in {
+		tty := &rawTerminal{f: os.Stdin}
+		tty.makeRaw()
+		defer tty.reset()
+		stopInput = startInputCopy(&jobControl{ptmx: ptmx, pid: cmd.Process.Pid, tty: tty, resize: inheritSize}, os.Stdin)
+	}
+	// The child leads its own session; with stdin raw Ctrl-C reaches it as
+	// input, and otherwise the kernel signals our group, not the child's.
+	// Either way any signal we get is forwarded to the child's group.
+	stopSignals := forwardSignals(cmd, true)
 
 	err = cmd.Wait()
+	received := stopSignals()
+	stopInput()
 	<-outputDone
+	<-errDone
 
-	code := exitCode(err)
-	return Result{
-		ExitCode:     code,
-		Mode:         "pty",
+	res := Result{
+		Mode:         s.mode(),
+		StdoutTail:   outTail.String(),
+		StderrTail:   errTail.String(),
 		CombinedTail: combined.String(),
-	}, err
+		StdoutBytes:  outTail.Total(),
+		StderrBytes:  errTail.Total(),
+		Truncated:    outTail.Truncated() || errTail.Truncated() || combined.Truncated(),
+		FullScreen:   combined.FullScreen(),
+	}
+	res.finish(cmd.ProcessState, err, received)
+	return res, err
+}
+
+// terminalWriter is where PTY output that isn't the child's stdout goes:
+// stderr when that is our terminal, else /dev/tty.
+func terminalWriter() (io.Writer, func()) {
+	if isTerminal(os.Stderr) {
+		return os.Stderr, func() {}
+	}
+	if f, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		return f, func() { _ = f.Close() }
+	}
+	return io.Discard, func() {}
+}
+
+// tailWriter is a Capture or a Screen.
+type tailWriter interface {
+	io.Writer
+	String() string
+	Total() int64
+	Truncated() bool
+}
+
+// capture returns a Screen for a stream on a PTY and a Capture for a pipe.
+func capture(onPTY bool) tailWriter {
+	if onPTY {
+		return NewScreen(captureHeadBytes, captureTailBytes)
+	}
+	return NewCapture(captureHeadBytes, captureTailBytes)
+}
+
+// streamWriter copies one of the child's streams to its destination and into
+// the tails. All streams share mu, so their chunks reach the screen and the
+// combined tail whole and in the order they were read.
+type streamWriter struct {
+	mu             *sync.Mutex
+	dst            io.Writer
+	tail, combined io.Writer
+}
+
+func (w streamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.tail != nil {
+		_, _ = w.tail.Write(p)
+	}
+	_, _ = w.combined.Write(p)
+	return w.dst.Write(p)
 }
@@ -9,12 +9,15 @@ import (
 	"os/exec"
 
 	"github.com/creack/pty"
-	"golang.org/x/term"
 )
 
-func runPTY(exe string, args []string) (Result, error) {
+// ptySupported: ConPTY takes over the whole console, so it is only used when
+// both stdin and stdout are terminals.
+func ptySupported(s streams) bool { return s.in && s.out }
+
+func runPTY(exe string, args, env []string, _ streams) (Result, error) {
 	cmd := exec.CommandContext(context.Background(), exe, args...)
-	cmd.Env = SanitizedEnv()
+	cmd.Env = env
 
 	// pty.Start on Windows uses ConPTY if available.
 	ptmx, err := pty.Start(cmd)
@@ -23,12 +26,8 @@ func runPTY(exe string, args []string) (Result, error) {
 	}
 	defer func() { _ = ptmx.Close() }()
 
-	if oldState, err := term.MakeRaw(int(os.Stdin.Fd())); err == nil {
-		defer func() {
-			if err := term.Restore(int(os.Stdin.Fd()), oldState); err != nil {
-				_ = err // best-effort
-			}
-		}()
+	if restore, err := MakeRaw(os.Stdin); err == nil {
+		defer restore()
 	}
 
 	// Windows doesn't use SIGWINCH for resize events in the same way.
@@ -40,7 +39,7 @@ func runPTY(exe string, args []string) (Result, error) {
 	// TODO: Monitor console resize events on Windows?
 	// For now, no dynamic resize loop.
 
-	combined := NewTail(64 * 1024)
+	combined := NewScreen(captureHeadBytes, captureTailBytes)
 
 	outputDone := make(chan struct{})
 	go func() {
@@ -59,10 +58,14 @@ func runPTY(exe string, args []string) (Result, error) {
 	err = cmd.Wait()
 	<-outputDone
 
-	code := exitCode(err)
-	return Result{
-		ExitCode:     code,
+	res := Result{
 		Mode:         "pty",
 		CombinedTail: combined.String(),
-	}, err
+		// ConPTY merges the streams; all of it counts as stdout.
+		StdoutBytes: combined.Total(),
+		Truncated:   combined.Truncated(),
+		FullScreen:  combined.FullScreen(),
+	}
+	res.finish(cmd.ProcessState, err, nil)
+	return res, err
 }
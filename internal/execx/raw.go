@@ -0,0 +1,22 @@
+package execx
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// MakeRaw puts the terminal f into raw mode and returns a func that restores
+// its previous state. Restore errors are ignored (best-effort).
+func MakeRaw(f *os.File) (restore func(), err error) {
+	fd := int(f.Fd())
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return func() {}, err
+	}
+	return func() {
+		if err := term.Restore(fd, oldState); err != nil {
+			_ = err // best-effort
+		}
+	}, nil
+}
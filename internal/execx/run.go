@@ -2,6 +2,8 @@ package execx
 
 import (
 	"os"
+	"strings"
+	"time"
 
 	"golang.org/x/term"
 )
@@ -25,17 +27,118 @@ func IsTTY() bool {
 	return true
 }
 
+// streams records which of our standard streams are terminals. The child
+// gets a PTY for exactly those and plain pipes for the rest, so
+// `git log | less` still pages in color and `echo x | gh api --input -`
+// still reads its input.
+type streams struct{ in, out, err bool }
+
+func isTerminal(f *os.File) bool {
+	fd := int(f.Fd())
+	if !term.IsTerminal(fd) {
+		return false
+	}
+	// As in IsTTY: a terminal whose state can't be read is treated as gone.
+	_, err := term.GetState(fd)
+	return err == nil
+}
+
+func terminalStreams() streams {
+	return streams{in: isTerminal(os.Stdin), out: isTerminal(os.Stdout), err: isTerminal(os.Stderr)}
+}
+
+func (s streams) any() bool { return s.in || s.out || s.err }
+
+// mode renders the layout for Result.Mode, e.g. "pty:out,err pipe:in".
+func (s streams) mode() string {
+	if !s.any() {
+		return "pipes"
+	}
+	var ptys, pipes []string
+	for _, st := range []struct {
+		name string
+		tty  bool
+	}{{"in", s.in}, {"out", s.out}, {"err", s.err}} {
+		if st.tty {
+			ptys = append(ptys, st.name)
+		} else {
+			pipes = append(pipes, st.name)
+		}
+	}
+	mode := "pty:" + strings.Join(ptys, ",")
+	if len(pipes) > 0 {
+		mode += " pipe:" + strings.Join(pipes, ",")
+	}
+	return mode
+}
+
 type Result struct {
-	ExitCode     int
-	Mode         string // "pty" or "pipes"
+	ExitCode int
+	// Mode is "pipes" or the per-stream layout: "pty:in,out,err" when all
+	// three are terminals, "pty:out,err pipe:in" when stdin is piped, and so
+	// on. Stdout and stderr are captured apart in StdoutTail and StderrTail
+	// either way. "pty" (one console for everything, captured only in
+	// CombinedTail) is what Windows records.
+	Mode         string
 	StdoutTail   string
 	StderrTail   string
 	CombinedTail string
+	// StdoutBytes and StderrBytes count everything the child wrote, terminal
+	// escapes included. The tails keep only the start and the end of each
+	// stream; Truncated is set when the middle of one was dropped (an
+	// "[... output omitted ...]" line marks the spot).
+	StdoutBytes int64
+	StderrBytes int64
+	Truncated   bool
+	// UserCPU and SysCPU are the CPU time the child (and the children it
+	// waited for) used; MaxRSS is its peak resident set in bytes, 0 where the
+	// OS doesn't report it.
+	UserCPU time.Duration
+	SysCPU  time.Duration
+	MaxRSS  int64
+	// Signal names the signal that ended the run ("SIGINT"): one that killed
+	// the child, or one the shim received and forwarded that made it fail.
+	Signal string
+	// FullScreen is set when the child drew on the terminal's alternate
+	// screen (an editor, a pager, top): its captured output is whatever was
+	// left on the main screen and says nothing about the command.
+	FullScreen bool
+	// Interrupted is set when the shim itself was signalled and the child
+	// died from it or exited with a failure. A child that handled the signal
+	// and exited 0 finished normally. ExitCode is always the child's.
+	Interrupted bool
+}
+
+// finish fills in the exit status and resource usage once the child is gone.
+// received is the signal forwarded while it ran, if any.
+func (r *Result) finish(ps *os.ProcessState, err error, received os.Signal) {
+	if ps != nil {
+		r.UserCPU = ps.UserTime()
+		r.SysCPU = ps.SystemTime()
+		r.MaxRSS = maxRSS(ps)
+	}
+	r.ExitCode = exitCode(err)
+	if sig := exitSignal(err); sig != nil {
+		r.Signal = signalName(sig)
+	}
+	if received != nil && r.ExitCode != 0 {
+		r.Interrupted = true
+		if r.Signal == "" {
+			r.Signal = signalName(received)
+		}
+	}
 }
 
+// Run runs exe with the shim dir removed from PATH, so tools it runs in turn
+// reach the real binaries.
 func Run(exe string, args []string) (Result, error) {
-	if IsTTY() {
-		return runPTY(exe, args)
+	return RunEnv(exe, args, SanitizedEnv())
+}
+
+// RunEnv runs exe with env as its environment.
+func RunEnv(exe string, args, env []string) (Result, error) {
+	if s := terminalStreams(); s.any() && ptySupported(s) {
+		return runPTY(exe, args, env, s)
 	}
-	return runPipes(exe, args)
+	return runPipes(exe, args, env)
 }
@@ -2,6 +2,7 @@ package execx
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	"golang.org/x/term"
@@ -43,8 +44,8 @@ func TestRun_FallsBackToPipesWhenPTYUnavailable(t *testing.T) {
 		t.Errorf("expected exit code 0, got %d", res.ExitCode)
 	}
 
-	// Verify that some mode was used (either "pty" or "pipes")
-	if res.Mode != "pty" && res.Mode != "pipes" {
+	// Verify that some mode was used (a PTY layout or "pipes")
+	if !strings.HasPrefix(res.Mode, "pty") && res.Mode != "pipes" {
 		t.Errorf("unexpected mode: %q", res.Mode)
 	}
 }
@@ -0,0 +1,16 @@
+//go:build darwin || ios
+
+package execx
+
+import (
+	"os"
+	"syscall"
+)
+
+// maxRSS is the child's peak resident set in bytes (ru_maxrss is in bytes here).
+func maxRSS(ps *os.ProcessState) int64 {
+	if ru, ok := ps.SysUsage().(*syscall.Rusage); ok {
+		return ru.Maxrss
+	}
+	return 0
+}
@@ -0,0 +1,16 @@
+//go:build !windows && !darwin && !ios
+
+package execx
+
+import (
+	"os"
+	"syscall"
+)
+
+// maxRSS is the child's peak resident set in bytes (ru_maxrss is in KiB here).
+func maxRSS(ps *os.ProcessState) int64 {
+	if ru, ok := ps.SysUsage().(*syscall.Rusage); ok {
+		return int64(ru.Maxrss) * 1024
+	}
+	return 0
+}
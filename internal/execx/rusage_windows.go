@@ -0,0 +1,8 @@
+//go:build windows
+
+package execx
+
+import "os"
+
+// maxRSS isn't reported on Windows.
+func maxRSS(*os.ProcessState) int64 { return 0 }
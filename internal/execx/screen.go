@@ -0,0 +1,400 @@
+package execx
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Bounds on what a Screen keeps, whatever the program writes: lines of
+// scrollback, columns per line (longer lines wrap, as on a terminal) and
+// cells in all.
+const (
+	maxScreenLines = 2000
+	maxScreenCols  = 1024
+	maxScreenCells = 256 * 1024
+)
+
+// Screen captures terminal output as a person would have seen it. It is a
+// small terminal emulator: printable text lands at the cursor, `\r`, `\b`
+// and cursor movement overwrite earlier text (progress bars collapse to
+// their last frame), erase sequences clear it, and colors, OSC sequences
+// (titles, hyperlinks) and other controls are dropped. Output drawn on the
+// alternate screen is discarded when the program leaves it, as terminals do;
+// FullScreen reports that it happened. Like a Capture it keeps the start of
+// the text as well as its end.
+//
+// Lines only wrap at maxScreenCols, however narrow the terminal, and cursor
+// moves stop at the edges of the screen, so a stray escape sequence can't
+// make it allocate more than its bounds.
+type Screen struct {
+	main, alt *grid
+	cur       *grid
+	rows      int
+	usedAlt   bool
+	total     int64
+
+	head             []byte // lines scrolled out of the scrollback, up to headMax
+	headMax, tailMax int
+	headFull         bool
+	dropped          int // lines lost after the head filled up
+
+	state   int
+	params  []byte
+	pending []byte // an incomplete UTF-8 sequence
+}
+
+// grid is one screen buffer: scrollback plus the visible rows, which start
+// at top.
+type grid struct {
+	lines              [][]rune
+	row, col, top      int
+	savedRow, savedCol int
+	cells              int // total length of lines
+}
+
+const (
+	stGround = iota
+	stEsc
+	stEscSkip // ESC ( B and friends: skip one byte
+	stCSI
+	stOSC
+	stOSCEsc
+)
+
+// NewScreen returns a Screen whose String keeps the first headBytes and the
+// last tailBytes of the text.
+func NewScreen(headBytes, tailBytes int) *Screen {
+	s := &Screen{main: &grid{}, rows: 24, headMax: headBytes, tailMax: tailBytes}
+	s.cur = s.main
+	return s
+}
+
+// SetRows sets the number of visible rows, which absolute cursor moves are
+// relative to.
+func (s *Screen) SetRows(rows int) {
+	if rows > 0 {
+		s.rows = min(rows, maxScreenLines)
+	}
+}
+
+// FullScreen reports whether the program switched to the alternate screen,
+// as full-screen TUIs (editors, pagers, top) do.
+func (s *Screen) FullScreen() bool { return s.usedAlt }
+
+// Total is the number of bytes written, escape sequences included.
+func (s *Screen) Total() int64 { return s.total }
+
+func (s *Screen) Write(p []byte) (int, error) {
+	s.total += int64(len(p))
+	for i := 0; i < len(p); i++ {
+		b := p[i]
+		switch s.state {
+		case stGround:
+			if b >= utf8.RuneSelf || len(s.pending) > 0 {
+				s.pending = append(s.pending, b)
+				if utf8.FullRune(s.pending) {
+					r, _ := utf8.DecodeRune(s.pending)
+					s.pending = s.pending[:0]
+					s.cur.put(r, s.rows)
+					s.trim()
+				}
+				continue
+			}
+			s.control(b)
+		case stEsc:
+			s.escape(b)
+		case stEscSkip:
+			s.state = stGround
+		case stCSI:
+			if b >= 0x40 && b <= 0x7e {
+				s.csi(b)
+				s.state = stGround
+				continue
+			}
+			s.params = append(s.params, b)
+		case stOSC:
+			switch b {
+			case 0x07:
+				s.state = stGround
+			case 0x1b:
+				s.state = stOSCEsc
+			}
+		case stOSCEsc:
+			if b == '\\' {
+				s.state = stGround
+			} else {
+				s.state = stOSC
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func (s *Screen) control(b byte) {
+	g := s.cur
+	switch b {
+	case 0x1b:
+		s.state = stEsc
+	case '\r':
+		g.col = 0
+	case '\n', '\v', '\f':
+		g.moveTo(g.row+1, 0, s.rows)
+	case '\b':
+		if g.col > 0 {
+			g.col--
+		}
+	case '\t':
+		g.col = min((g.col/8+1)*8, maxScreenCols-1)
+	default:
+		if b >= 0x20 && b != 0x7f {
+			g.put(rune(b), s.rows)
+		}
+	}
+	s.trim()
+}
+
+func (s *Screen) escape(b byte) {
+	s.state = stGround
+	g := s.cur
+	switch b {
+	case '[':
+		s.state = stCSI
+		s.params = s.params[:0]
+	case ']', 'P', '_', '^', 'X':
+		// OSC, DCS and the like run to BEL or ST; their text is never shown.
+		s.state = stOSC
+	case '(', ')', '*', '+', '#', '%':
+		s.state = stEscSkip
+	case '7':
+		g.savedRow, g.savedCol = g.row, g.col
+	case '8':
+		g.moveTo(g.savedRow, g.savedCol, s.rows)
+	case 'M':
+		if g.row > g.top {
+			g.row--
+		}
+	case 'E':
+		g.moveTo(g.row+1, 0, s.rows)
+	}
+}
+
+func (s *Screen) csi(final byte) {
+	private := len(s.params) > 0 && s.params[0] == '?'
+	raw := strings.TrimLeft(string(s.params), "?>=<")
+	var nums []int
+	for _, f := range strings.Split(raw, ";") {
+		n, _ := strconv.Atoi(f)
+		nums = append(nums, n)
+	}
+	arg := func(i, def int) int {
+		if i < len(nums) && nums[i] > 0 {
+			return nums[i]
+		}
+		return def
+	}
+
+	g := s.cur
+	// Cursor moves stop at the screen's edges; only output scrolls.
+	bottom := g.top + s.rows - 1
+	switch final {
+	case 'A':
+		g.moveTo(max(g.row-arg(0, 1), g.top), g.col, s.rows)
+	case 'B':
+		g.moveTo(min(g.row+arg(0, 1), bottom), g.col, s.rows)
+	case 'C':
+		g.col = min(g.col+arg(0, 1), maxScreenCols-1)
+	case 'D':
+		g.col = max(g.col-arg(0, 1), 0)
+	case 'E':
+		g.moveTo(min(g.row+arg(0, 1), bottom), 0, s.rows)
+	case 'F':
+		g.moveTo(max(g.row-arg(0, 1), g.top), 0, s.rows)
+	case 'G', '`':
+		g.col = min(arg(0, 1), maxScreenCols) - 1
+	case 'd':
+		g.moveTo(min(g.top+arg(0, 1)-1, bottom), g.col, s.rows)
+	case 'H', 'f':
+		g.moveTo(min(g.top+arg(0, 1)-1, bottom), min(arg(1, 1), maxScreenCols)-1, s.rows)
+	case 'K':
+		g.eraseLine(arg(0, 0))
+	case 'J':
+		g.eraseScreen(arg(0, 0))
+	case 's':
+		g.savedRow, g.savedCol = g.row, g.col
+	case 'u':
+		g.moveTo(g.savedRow, g.savedCol, s.rows)
+	case 'h', 'l':
+		if !private {
+			return
+		}
+		for _, n := range nums {
+			if n == 47 || n == 1047 || n == 1049 {
+				s.setAlt(final == 'h')
+			}
+		}
+	}
+	s.trim()
+}
+
+func (s *Screen) setAlt(on bool) {
+	if on {
+		s.usedAlt = true
+		s.alt = &grid{}
+		s.cur = s.alt
+		return
+	}
+	s.alt = nil
+	s.cur = s.main
+}
+
+// trim drops the oldest lines once the scrollback has more than
+// maxScreenLines or maxScreenCells, keeping the cursor's line. Lines dropped
+// from the main screen go to the head while it has room.
+func (s *Screen) trim() {
+	g := s.cur
+	n := max(len(g.lines)-maxScreenLines, 0)
+	cells := g.cells
+	for _, l := range g.lines[:n] {
+		cells -= len(l)
+	}
+	for ; n < g.row && cells > maxScreenCells; n++ {
+		cells -= len(g.lines[n])
+	}
+	if n == 0 {
+		return
+	}
+	for _, l := range g.lines[:n] {
+		if g == s.main {
+			s.keepHead(strings.TrimRight(string(l), " "))
+		}
+		g.cells -= len(l)
+	}
+	g.lines = g.lines[n:]
+	g.row = max(g.row-n, 0)
+	g.top = max(g.top-n, 0)
+	g.savedRow = max(g.savedRow-n, 0)
+}
+
+func (s *Screen) keepHead(line string) {
+	if s.headFull || len(s.head)+len(line)+1 > s.headMax {
+		if !s.headFull {
+			// Like a Capture's head, keep the start of what doesn't fit.
+			s.head = append(s.head, trimRuneEnd(line[:s.headMax-len(s.head)])...)
+		}
+		s.headFull = true
+		s.dropped++
+		return
+	}
+	s.head = append(s.head, line...)
+	s.head = append(s.head, '\n')
+}
+
+// String returns the main screen's text without trailing blanks: the head
+// and the tail, with omittedMarker between them if anything was dropped.
+func (s *Screen) String() string {
+	out, _ := s.render()
+	return out
+}
+
+// Truncated reports whether text between the head and the tail was dropped.
+func (s *Screen) Truncated() bool {
+	_, truncated := s.render()
+	return truncated
+}
+
+func (s *Screen) render() (string, bool) {
+	lines := make([]string, 0, len(s.main.lines))
+	for _, l := range s.main.lines {
+		lines = append(lines, strings.TrimRight(string(l), " "))
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	text := ""
+	if len(lines) > 0 {
+		text = strings.Join(lines, "\n") + "\n"
+	}
+	if s.dropped == 0 {
+		return clip(string(s.head)+text, s.headMax, s.tailMax)
+	}
+	if len(text) > s.tailMax {
+		text = trimRuneStart(text[len(text)-s.tailMax:])
+	}
+	return string(s.head) + omittedMarker + text, true
+}
+
+func (g *grid) moveTo(row, col, rows int) {
+	g.row, g.col = max(row, 0), max(col, 0)
+	for len(g.lines) <= g.row {
+		g.lines = append(g.lines, nil)
+	}
+	if g.row >= g.top+rows {
+		g.top = g.row - rows + 1
+	}
+}
+
+func (g *grid) put(r rune, rows int) {
+	if g.col >= maxScreenCols {
+		g.moveTo(g.row+1, 0, rows)
+	}
+	g.moveTo(g.row, g.col, rows)
+	l := g.lines[g.row]
+	for len(l) < g.col {
+		l = append(l, ' ')
+	}
+	if g.col < len(l) {
+		l[g.col] = r
+	} else {
+		l = append(l, r)
+	}
+	g.setLine(g.row, l)
+	g.col++
+}
+
+// setLine replaces line i, keeping count of the cells in use.
+func (g *grid) setLine(i int, l []rune) {
+	g.cells += len(l) - len(g.lines[i])
+	g.lines[i] = l
+}
+
+func (g *grid) eraseLine(mode int) {
+	if g.row >= len(g.lines) {
+		return
+	}
+	l := g.lines[g.row]
+	switch mode {
+	case 0:
+		if g.col < len(l) {
+			g.setLine(g.row, l[:g.col])
+		}
+	case 1:
+		for i := 0; i <= g.col && i < len(l); i++ {
+			l[i] = ' '
+		}
+	case 2:
+		g.setLine(g.row, nil)
+	}
+}
+
+func (g *grid) eraseScreen(mode int) {
+	switch mode {
+	case 0:
+		g.eraseLine(0)
+		for i := g.row + 1; i < len(g.lines); i++ {
+			g.setLine(i, nil)
+		}
+		if g.row+1 < len(g.lines) {
+			g.lines = g.lines[:g.row+1]
+		}
+	case 1:
+		for i := g.top; i < g.row && i < len(g.lines); i++ {
+			g.setLine(i, nil)
+		}
+		g.eraseLine(1)
+	default:
+		for i := g.top; i < len(g.lines); i++ {
+			g.setLine(i, nil)
+		}
+	}
+}
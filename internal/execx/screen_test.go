@@ -0,0 +1,127 @@
+package execx
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestScreen(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{"plain", "hello\r\nworld\r\n", "hello\nworld\n"},
+		{"colors", "\x1b[1;31merror:\x1b[0m bad flag\r\n", "error: bad flag\n"},
+		{"carriage return progress", "  0%\r 50%\r100%\r\ndone\r\n", "100%\ndone\n"},
+		{"erase line", "Downloading... 3/10\r\x1b[Kok\r\n", "ok\n"},
+		{"cursor up redraw", "a 1\r\nb 1\r\n\x1b[2Aa 2\r\nb 2\r\n", "a 2\nb 2\n"},
+		{"backspace", "abc\b\bXY\r\n", "aXY\n"},
+		{"osc hyperlink", "see \x1b]8;;https://example.com\x1b\\docs\x1b]8;;\x1b\\ now\r\n", "see docs now\n"},
+		{"osc title bel", "\x1b]0;title\x07text\r\n", "text\n"},
+		{"alternate screen", "before\r\n\x1b[?1049h\x1b[H\x1b[2Jtui frame\x1b[?1049lafter\r\n", "before\nafter\n"},
+		{"clear screen", "old\r\n\x1b[H\x1b[2Jnew\r\n", "new\n"},
+		{"cursor position", "\x1b[2;3Hx\r\n", "\n  x\n"},
+		{"utf8", "caf\xc3\xa9 \xe2\x9c\x93\r\n", "café ✓\n"},
+		{"charset select", "\x1b(Bplain\r\n", "plain\n"},
+		{"trailing blanks", "x   \r\n\r\n\r\n", "x\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := NewScreen(1024, 1024)
+			_, _ = s.Write([]byte(c.in))
+			if got := s.String(); got != c.want {
+				t.Fatalf("String() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestScreen_SplitWrites(t *testing.T) {
+	in := []byte("\x1b[31mcaf\xc3\xa9\x1b[0m\x1b]8;;u\x1b\\!\r\n")
+	s := NewScreen(1024, 1024)
+	for i := range in {
+		_, _ = s.Write(in[i : i+1])
+	}
+	if got := s.String(); got != "café!\n" {
+		t.Fatalf("String() = %q", got)
+	}
+}
+
+func TestScreen_FullScreen(t *testing.T) {
+	s := NewScreen(1024, 1024)
+	_, _ = s.Write([]byte("plain output\r\n"))
+	if s.FullScreen() {
+		t.Fatal("FullScreen() = true before the alternate screen was used")
+	}
+	_, _ = s.Write([]byte("\x1b[?1049h~\r\n~\r\n\x1b[?1049l"))
+	if !s.FullScreen() {
+		t.Fatal("FullScreen() = false after the alternate screen was used")
+	}
+}
+
+func TestScreen_KeepsHeadAndTail(t *testing.T) {
+	s := NewScreen(6, 7)
+	_, _ = s.Write([]byte("usage\r\nlots\r\nof\r\nnoise\r\nlast 1\r\n"))
+	if got, want := s.String(), "usage\n"+omittedMarker+"last 1\n"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+	if !s.Truncated() {
+		t.Fatal("Truncated() = false")
+	}
+}
+
+func TestScreen_HeadSurvivesScrollback(t *testing.T) {
+	s := NewScreen(16, 64)
+	_, _ = s.Write([]byte("error: bad flag\r\n"))
+	for i := 0; i < maxScreenLines+10; i++ {
+		_, _ = s.Write([]byte("usage line\r\n"))
+	}
+	got := s.String()
+	if !strings.HasPrefix(got, "error: bad flag\n"+omittedMarker) || !strings.HasSuffix(got, "usage line\n") {
+		t.Fatalf("String() = %q", got)
+	}
+}
+
+func TestScreen_BoundsCursorMoves(t *testing.T) {
+	for _, in := range []string{
+		"\x1b[50000000;1Hx\r\n",
+		"\x1b[50000000Gx\r\n",
+		"\x1b[50000000Bx\r\n",
+		"\x1b[50000000Cx\r\n",
+		"\x1b[50000000dx\r\n",
+	} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		s := NewScreen(1024, 1024)
+		s.SetRows(24)
+		_, _ = s.Write([]byte(in))
+		runtime.ReadMemStats(&after)
+		if grown := after.TotalAlloc - before.TotalAlloc; grown > 1<<20 {
+			t.Errorf("%q allocated %d bytes", in, grown)
+		}
+		if got := s.String(); !strings.Contains(got, "x") || len(got) > 2*maxScreenCols+24 {
+			t.Errorf("%q: String() = %d bytes, want x within the screen", in, len(got))
+		}
+	}
+}
+
+func TestScreen_LongLineKeepsHeadAndTail(t *testing.T) {
+	line := "start " + strings.Repeat("0123456789", 400_000) + " end"
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	s := NewScreen(64, 256)
+	_, _ = s.Write([]byte(line))
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	if held := int64(after.HeapAlloc) - int64(before.HeapAlloc); held > 8<<20 {
+		t.Fatalf("a %d-byte line left %d bytes on the heap", len(line), held)
+	}
+	if s.cur.cells > maxScreenCells+maxScreenCols {
+		t.Fatalf("screen holds %d cells", s.cur.cells)
+	}
+	got := s.String()
+	if !strings.HasPrefix(got, "start ") || !strings.HasSuffix(got, " end\n") || !s.Truncated() || len(got) > 64+len(omittedMarker)+256 {
+		t.Fatalf("String() = %d bytes: %.80q...%q", len(got), got, got[max(len(got)-40, 0):])
+	}
+}
@@ -10,6 +10,11 @@ func ShellJoin(argv []string) string {
 	return shellquote.Join(argv...)
 }
 
+// ShellSplit is the inverse of ShellJoin.
+func ShellSplit(s string) ([]string, error) {
+	return shellquote.Split(s)
+}
+
 func ContainsFold(haystack, needle string) bool {
 	return strings.Contains(strings.ToLower(haystack), strings.ToLower(needle))
 }
@@ -0,0 +1,85 @@
+//go:build !windows
+
+package execx
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// forwardedSignals are passed on to the child instead of killing the shim, so
+// the run is still recorded and the child is never orphaned.
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// ownProcessGroup reports whether a piped child should get its own process
+// group. With a controlling terminal, job control belongs to the shell: the
+// child stays in our group, so Ctrl-C and `fg` reach it directly. Without one
+// (agents, CI, cron), nothing signals the child but us.
+func ownProcessGroup() bool {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return true
+	}
+	_ = tty.Close()
+	return false
+}
+
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// forwardSignals relays forwardedSignals to the started cmd until stop is
+// called; stop returns the last signal received, or nil. With group set the
+// whole process group led by cmd gets them. Otherwise the child shares our
+// terminal's process group and already received any Ctrl-C or Ctrl-\ itself,
+// so only SIGTERM and SIGHUP are passed on.
+func forwardSignals(cmd *exec.Cmd, group bool) (stop func() os.Signal) {
+	ch := make(chan os.Signal, len(forwardedSignals))
+	signal.Notify(ch, forwardedSignals...)
+
+	var mu sync.Mutex
+	var got os.Signal
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for sig := range ch {
+			mu.Lock()
+			got = sig
+			mu.Unlock()
+			s, _ := sig.(syscall.Signal)
+			switch {
+			case group:
+				_ = unix.Kill(-cmd.Process.Pid, s)
+			case s == syscall.SIGTERM || s == syscall.SIGHUP:
+				_ = cmd.Process.Signal(s)
+			}
+		}
+	}()
+
+	return func() os.Signal {
+		signal.Stop(ch)
+		close(ch)
+		<-done
+		mu.Lock()
+		defer mu.Unlock()
+		return got
+	}
+}
+
+// signalName is the conventional name of sig ("SIGINT").
+func signalName(sig os.Signal) string {
+	if s, ok := sig.(syscall.Signal); ok {
+		if name := unix.SignalName(s); name != "" {
+			return name
+		}
+	}
+	return sig.String()
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package execx
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Console Ctrl-C and Ctrl-Break reach every process attached to the console,
+// so the child gets them without forwarding.
+
+func ownProcessGroup() bool { return false }
+
+func setProcessGroup(*exec.Cmd) {}
+
+func forwardSignals(*exec.Cmd, bool) (stop func() os.Signal) {
+	return func() os.Signal { return nil }
+}
+
+func signalName(sig os.Signal) string { return sig.String() }
@@ -1,8 +1,12 @@
 package execx
 
+// Tail keeps the last cap bytes written to it in a fixed ring buffer, so a
+// chatty tool costs one copy per write however much it prints.
 type Tail struct {
-	buf []byte
-	cap int
+	buf  []byte
+	cap  int
+	pos  int // next write offset
+	full bool
 }
 
 func NewTail(maxBytes int) *Tail {
@@ -10,11 +14,41 @@ func NewTail(maxBytes int) *Tail {
 }
 
 func (t *Tail) Write(p []byte) (int, error) {
-	t.buf = append(t.buf, p...)
-	if len(t.buf) > t.cap {
-		t.buf = t.buf[len(t.buf)-t.cap:]
+	n := len(p)
+	if t.cap <= 0 {
+		return n, nil
 	}
-	return len(p), nil
+	if t.buf == nil {
+		t.buf = make([]byte, t.cap)
+	}
+	if len(p) >= t.cap {
+		copy(t.buf, p[len(p)-t.cap:])
+		t.pos, t.full = 0, true
+		return n, nil
+	}
+	c := copy(t.buf[t.pos:], p)
+	if c < len(p) {
+		copy(t.buf, p[c:])
+		t.full = true
+	}
+	t.pos = (t.pos + len(p)) % t.cap
+	if t.pos == 0 {
+		t.full = true
+	}
+	return n, nil
 }
 
-func (t *Tail) String() string { return string(t.buf) }
+// Len is the number of bytes held.
+func (t *Tail) Len() int {
+	if t.full {
+		return t.cap
+	}
+	return t.pos
+}
+
+func (t *Tail) String() string {
+	if !t.full {
+		return string(t.buf[:t.pos])
+	}
+	return string(t.buf[t.pos:]) + string(t.buf[:t.pos])
+}
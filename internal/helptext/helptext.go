@@ -0,0 +1,155 @@
+// Package helptext extracts a tool's vocabulary (subcommands and flags) from
+// its --help output. It understands the layouts produced by cobra, clap,
+// argparse and GNU getopt-style tools, plus git's command list and man pages.
+package helptext
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/execx"
+)
+
+// Vocab is what a help text says a command accepts.
+type Vocab struct {
+	Subcommands []string
+	Flags       []string
+}
+
+// Timeout bounds each help invocation; tools that hang or prompt are skipped.
+const Timeout = 2 * time.Second
+
+// maxOutput caps how much help text is parsed (man pages can be huge).
+const maxOutput = 512 << 10
+
+// Fetch runs `exe [sub] --help` and parses the output. Tools that exit non-zero
+// but print help are accepted; anything else yields an empty Vocab.
+func Fetch(exe, sub string) Vocab {
+	args := []string{"--help"}
+	if sub != "" {
+		args = []string{sub, "--help"}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, exe, args...)
+	// Never prompt, never page.
+	cmd.Stdin = strings.NewReader("")
+	// Without the shims on PATH: what the tool runs in turn isn't recorded.
+	cmd.Env = append(execx.SanitizedEnv(), "PAGER=cat", "MANPAGER=cat", "GIT_PAGER=cat", "MANWIDTH=120", "NO_COLOR=1")
+	out, _ := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return Vocab{}
+	}
+	if len(out) > maxOutput {
+		out = out[:maxOutput]
+	}
+	return Parse(string(out))
+}
+
+var (
+	overstrike = regexp.MustCompile(".\x08")
+	ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
+	flagRe     = regexp.MustCompile(`(?:^|[\s,\[|(])(--?[A-Za-z0-9][A-Za-z0-9_-]*)`)
+	commandRe  = regexp.MustCompile(`^[a-z][a-z0-9_-]*(?:,\s*[a-z][a-z0-9_-]*)*(?:\s{2,}|\t|$)`)
+	choicesRe  = regexp.MustCompile(`^\{([a-z0-9_,-]+)\}`)
+)
+
+// Parse extracts subcommands and flags from help text.
+//
+// Flags come from indented lines that start with a dash ("  -a, --all  ...",
+// "      --block-size=SIZE", "  -o OUTPUT, --output OUTPUT"); only the part
+// before the description column is read. Subcommands come from indented
+// "name  description" lines inside a section whose heading mentions commands
+// ("Available Commands:", "Commands:", "SUBCOMMANDS:", "These are common Git
+// commands...") and from argparse's "{a,b,c}" choices.
+func Parse(text string) Vocab {
+	text = overstrike.ReplaceAllString(text, "")
+	text = ansiEscape.ReplaceAllString(text, "")
+
+	subs := map[string]bool{}
+	flags := map[string]bool{}
+	inCommands := false
+	for _, raw := range strings.Split(text, "\n") {
+		line := strings.TrimRight(raw, " \t\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " \t")
+		indented := len(trimmed) < len(line)
+
+		if !indented {
+			lower := strings.ToLower(line)
+			switch {
+			case strings.Contains(lower, "command") || strings.HasPrefix(lower, "positional arguments"):
+				inCommands = true
+			case strings.HasSuffix(line, ":") || isManHeading(line):
+				// "Flags:", "Options:", "Usage:", "OPTIONS" end a command list;
+				// git's prose sub-headings don't.
+				inCommands = false
+			}
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "-") {
+			for _, m := range flagRe.FindAllStringSubmatch(specPart(trimmed), -1) {
+				flags[m[1]] = true
+			}
+			continue
+		}
+
+		if !inCommands {
+			continue
+		}
+		if m := choicesRe.FindStringSubmatch(trimmed); m != nil {
+			for _, c := range strings.Split(m[1], ",") {
+				if c != "" {
+					subs[c] = true
+				}
+			}
+			continue
+		}
+		// "name  description" or clap's "name, alias  description".
+		if m := commandRe.FindString(trimmed); m != "" {
+			for _, c := range strings.Split(m, ",") {
+				subs[strings.TrimSpace(c)] = true
+			}
+		}
+	}
+	return Vocab{Subcommands: sortedKeys(subs), Flags: sortedKeys(flags)}
+}
+
+// specPart is the flag spec before the description column (two spaces or a
+// tab), e.g. "-a, --all" in "-a, --all   do not ignore entries".
+func specPart(s string) string {
+	if i := strings.Index(s, "  "); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// isManHeading matches man page section headings like "OPTIONS" or
+// "SEE ALSO".
+func isManHeading(s string) bool {
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && r != ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
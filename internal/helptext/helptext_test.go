@@ -0,0 +1,161 @@
+package helptext
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/joelklabo/ackchyually/internal/execx"
+)
+
+const cobraHelp = `A tool for things.
+
+Usage:
+  mytool [flags]
+  mytool [command]
+
+Available Commands:
+  completion  Generate the autocompletion script for the specified shell
+  help        Help about any command
+  status      Show status
+
+Flags:
+  -h, --help            help for mytool
+      --pretty string   output format
+  -v, --verbose         verbose output
+
+Global Flags:
+      --config string   config file
+
+Use "mytool [command] --help" for more information about a command.
+`
+
+const clapHelp = `Usage: rtool [OPTIONS] <COMMAND>
+
+Commands:
+  build, b  Compile the project
+  run       Run it
+  help      Print this message or the help of the given subcommand(s)
+
+Options:
+  -q, --quiet          Do not print log messages
+      --color <WHEN>   Coloring [default: auto] [possible values: auto, always, never]
+  -h, --help           Print help
+`
+
+const clap2Help = `rtool 0.1
+
+USAGE:
+    rtool [FLAGS] <SUBCOMMAND>
+
+FLAGS:
+    -h, --help       Prints help information
+
+SUBCOMMANDS:
+    check    Check the project
+    help     Prints this message
+`
+
+const argparseHelp = `usage: ptool [-h] [--dry-run] {init,sync} ...
+
+positional arguments:
+  {init,sync}
+    init       Initialize
+    sync       Sync things
+
+options:
+  -h, --help   show this help message and exit
+  -n, --dry-run
+               do nothing
+  -o OUTPUT, --output OUTPUT
+               write here
+`
+
+const gnuHelp = `Usage: ls [OPTION]... [FILE]...
+List information about the FILEs (the current directory by default).
+
+Mandatory arguments to long options are mandatory for short options too.
+  -a, --all                  do not ignore entries starting with .
+      --block-size=SIZE      with -l, scale sizes by SIZE when printing them;
+                               e.g., '--block-size=M'; see SIZE format below
+      --color[=WHEN]         color the output WHEN
+  -I, --ignore=PATTERN       do not list implied entries matching shell PATTERN
+`
+
+const gitHelp = `usage: git [-v | --version] [-h | --help] [-C <path>] [-c <name>=<value>]
+           <command> [<args>]
+
+These are common Git commands used in various situations:
+
+start a working area (see also: git help tutorial)
+   clone     Clone a repository into a new directory
+   init      Create an empty Git repository or reinitialize an existing one
+
+examine the history and state (see also: git help revisions)
+   log       Show commit logs
+   status    Show the working tree status
+
+'git help -a' and 'git help -g' list available subcommands and some
+concept guides.
+`
+
+const manPage = "GIT-LOG(1)\n\nNAME\n       git-log - Show commit logs\n\nOPTIONS\n" +
+	"       --follow\n           Continue listing the history of a file beyond renames.\n\n" +
+	"       -\b--\b-p\bpr\bre\bet\btt\bty\by[=<format>], --format=<format>\n           Pretty-print.\n"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantSubs  string
+		wantFlags string
+	}{
+		{"cobra", cobraHelp, "completion help status", "--config --help --pretty --verbose -h -v"},
+		{"clap", clapHelp, "b build help run", "--color --help --quiet -h -q"},
+		{"clap2", clap2Help, "check help", "--help -h"},
+		{"argparse", argparseHelp, "init sync", "--dry-run --help --output -h -n -o"},
+		{"gnu", gnuHelp, "", "--all --block-size --color --ignore -I -a"},
+		{"git", gitHelp, "clone init log status", ""},
+		{"man", manPage, "", "--follow --format --pretty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := Parse(tt.text)
+			if got := strings.Join(v.Subcommands, " "); got != tt.wantSubs {
+				t.Errorf("subcommands = %q, want %q", got, tt.wantSubs)
+			}
+			if got := strings.Join(v.Flags, " "); got != tt.wantFlags {
+				t.Errorf("flags = %q, want %q", got, tt.wantFlags)
+			}
+		})
+	}
+}
+
+func TestFetch_RunsWithoutShimsOnPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	shimDir := execx.ShimDir()
+	if err := os.MkdirAll(shimDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", shimDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// Lists a "shimmed" subcommand only if it can see the shims.
+	exe := filepath.Join(t.TempDir(), "tool")
+	//nolint:gosec
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\n"+
+		"echo 'Commands:'\n"+
+		"case \":$PATH:\" in *\":"+shimDir+":\"*) echo '  shimmed  saw the shims' ;; esac\n"+
+		"echo '  status   show status'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	v := Fetch(exe, "")
+	if strings.Join(v.Subcommands, " ") != "status" {
+		t.Fatalf("Fetch subcommands = %v; want the tool run without the shim dir on PATH", v.Subcommands)
+	}
+}
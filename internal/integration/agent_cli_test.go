@@ -20,6 +20,7 @@ func TestAgentCLI_SpawnViaPATH_LogsSuccessForBest(t *testing.T) {
 
 	tmp := t.TempDir()
 	home := filepath.Join(tmp, "home")
+	waitForIdentifier(t, home)
 	shimDir := filepath.Join(home, ".local", "share", "ackchyually", "shims")
 	realDir := filepath.Join(tmp, "real")
 	binDir := filepath.Join(tmp, "bin")
@@ -17,6 +17,7 @@ func TestIntegrateVerifyCodex_ConfigSanity(t *testing.T) {
 
 	tmp := t.TempDir()
 	home := filepath.Join(tmp, "home")
+	waitForIdentifier(t, home)
 	binDir := filepath.Join(tmp, "bin")
 
 	mkdirAll(t, home)
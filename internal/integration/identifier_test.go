@@ -0,0 +1,51 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// waitForIdentifier makes the test wait, before its temp dir is removed, for
+// the tool identifier a shim may have started in the background to be done
+// with home: its queue drained and its database connection closed. Call it
+// after t.TempDir so its cleanup runs first.
+func waitForIdentifier(t *testing.T, home string) {
+	t.Helper()
+	t.Cleanup(func() {
+		db := filepath.Join(home, ".local", "share", "ackchyually", "ackchyually.sqlite")
+		deadline := time.Now().Add(15 * time.Second)
+		for fileExists(db) && (identifyQueued(db) || fileExists(db+"-wal")) {
+			if time.Now().After(deadline) {
+				t.Errorf("background tool identifier still running after 15s")
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	})
+}
+
+// identifyQueued reports whether any binary is still queued for the
+// identifier (or the queue can't be read yet).
+func identifyQueued(path string) bool {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return true
+	}
+	defer db.Close()
+	var n int
+	if err := db.QueryRowContext(context.Background(), `SELECT COUNT(*) FROM tool_identify_queue`).Scan(&n); err != nil {
+		return true
+	}
+	return n > 0
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,90 @@
+//go:build !windows
+
+package integration
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/ackchyually/internal/store"
+)
+
+func TestIdentify_NewBinaryRunsFirstAndIsBackfilled(t *testing.T) {
+	root := repoRoot(t)
+
+	tmp := t.TempDir()
+	home := filepath.Join(tmp, "home")
+	waitForIdentifier(t, home)
+	shimDir := filepath.Join(home, ".local", "share", "ackchyually", "shims")
+	realDir := filepath.Join(tmp, "real")
+	binDir := filepath.Join(tmp, "bin")
+	workDir := filepath.Join(tmp, "work")
+
+	mkdirAll(t, shimDir)
+	mkdirAll(t, realDir)
+	mkdirAll(t, binDir)
+	mkdirAll(t, workDir)
+
+	ack := filepath.Join(binDir, "ackchyually")
+	build(t, root, "./cmd/ackchyually", ack)
+
+	// A slow version probe would hold up the first run if it were in line.
+	must(t, os.WriteFile(filepath.Join(realDir, "tool"), []byte("#!/bin/sh\n"+
+		"case \"$1\" in\n"+
+		"  --version) sleep 0.7; echo 2.0.1 ;;\n"+
+		"  version|-V|-v) exit 1 ;;\n"+
+		"  *) echo ran ;;\n"+
+		"esac\n"), 0o755))
+	must(t, os.Symlink(ack, filepath.Join(shimDir, "tool")))
+
+	cmd := exec.CommandContext(context.Background(), filepath.Join(shimDir, "tool"), "go")
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(),
+		"HOME="+home,
+		"PATH="+strings.Join([]string{shimDir, realDir, "/usr/bin", "/bin"}, string(os.PathListSeparator)),
+		"ACKCHYUALLY_AGENT=0",
+	)
+	start := time.Now()
+	out, err := cmd.CombinedOutput()
+	if err != nil || string(out) != "ran\n" {
+		t.Fatalf("shim: %v\n%s", err, out)
+	}
+	if d := time.Since(start); d > 600*time.Millisecond {
+		t.Fatalf("first run took %v; identification should not be in line", d)
+	}
+
+	t.Setenv("HOME", home)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var inv store.Invocation
+		var ti store.ToolIdentity
+		if err := store.WithDB(func(db *store.DB) error {
+			invs, err := db.ListInvocations("cwd:"+workDir, "tool", 1)
+			if err != nil || len(invs) == 0 {
+				return err
+			}
+			inv = invs[0]
+			if inv.ToolID != 0 {
+				ti, err = db.GetToolByID(inv.ToolID)
+			}
+			return err
+		}); err != nil {
+			t.Fatalf("read history: %v", err)
+		}
+		if inv.ToolID != 0 {
+			if ti.VersionStr != "tool 2.0.1" {
+				t.Fatalf("backfilled tool version = %q", ti.VersionStr)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tool_id was never backfilled: %+v", inv)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
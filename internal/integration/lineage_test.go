@@ -0,0 +1,90 @@
+//go:build !windows
+
+package integration
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLineage_RecordsNestedCallsWithoutSuggestions(t *testing.T) {
+	root := repoRoot(t)
+
+	tmp := t.TempDir()
+	home := filepath.Join(tmp, "home")
+	waitForIdentifier(t, home)
+	shimDir := filepath.Join(home, ".local", "share", "ackchyually", "shims")
+	realDir := filepath.Join(tmp, "real")
+	binDir := filepath.Join(tmp, "bin")
+	workDir := filepath.Join(tmp, "work")
+
+	mkdirAll(t, shimDir)
+	mkdirAll(t, realDir)
+	mkdirAll(t, binDir)
+	mkdirAll(t, workDir)
+
+	ack := filepath.Join(binDir, "ackchyually")
+	build(t, root, "./cmd/ackchyually", ack)
+
+	// outer is a composite build that runs inner twice; one call is wrong.
+	must(t, os.WriteFile(filepath.Join(realDir, "outer"), []byte("#!/bin/sh\n"+
+		"[ \"$1\" = build ] || { echo outer 1.0; exit 0; }\n"+
+		"inner --bad\n"+
+		"inner ok\n"), 0o755))
+	must(t, os.WriteFile(filepath.Join(realDir, "inner"), []byte("#!/bin/sh\n"+
+		"case \"$1\" in\n"+
+		"  --version|version|-V|-v) echo inner 1.0 ;;\n"+
+		"  ok) echo fine ;;\n"+
+		"  *) echo \"unknown flag: $1\" >&2; exit 2 ;;\n"+
+		"esac\n"), 0o755))
+	for _, tool := range []string{"outer", "inner"} {
+		must(t, os.Symlink(ack, filepath.Join(shimDir, tool)))
+	}
+
+	env := append(os.Environ(),
+		"HOME="+home,
+		"PATH="+strings.Join([]string{shimDir, realDir, "/usr/bin", "/bin"}, string(os.PathListSeparator)),
+		"ACKCHYUALLY_AGENT=1",
+		"ACKCHYUALLY_LINEAGE=1",
+	)
+	run := func(name string, args ...string) string {
+		t.Helper()
+		cmd := exec.CommandContext(context.Background(), name, args...)
+		cmd.Dir = workDir
+		cmd.Env = env
+		out, _ := cmd.CombinedOutput()
+		return string(out)
+	}
+
+	out := run(filepath.Join(shimDir, "outer"), "build")
+	if !strings.Contains(out, "unknown flag: --bad") || !strings.Contains(out, "fine") {
+		t.Fatalf("expected the nested calls' output, got:\n%s", out)
+	}
+	for _, l := range strings.Split(out, "\n") {
+		if strings.HasPrefix(l, "ackchyually:") && strings.Contains(l, "inner") {
+			t.Fatalf("nested call should not get suggestions, got:\n%s", out)
+		}
+	}
+
+	tree := run(ack, "history", "--tree")
+	lines := strings.Split(tree, "\n")
+	idx := func(sub string) int {
+		for i, l := range lines {
+			if strings.Contains(l, sub) {
+				return i
+			}
+		}
+		return -1
+	}
+	outerAt, badAt, okAt := idx("outer build"), idx("├─ inner --bad"), idx("└─ inner ok")
+	if outerAt < 0 || badAt != outerAt+1 || okAt != outerAt+2 {
+		t.Fatalf("expected inner calls nested under outer build, got:\n%s", tree)
+	}
+	if !strings.Contains(lines[badAt], "usage") {
+		t.Fatalf("expected the nested failure to be classified, got:\n%s", tree)
+	}
+}
@@ -0,0 +1,74 @@
+//go:build !windows
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPassthrough_ExecsRealToolWithoutRecording(t *testing.T) {
+	root := repoRoot(t)
+
+	tmp := t.TempDir()
+	home := filepath.Join(tmp, "home")
+	waitForIdentifier(t, home)
+	dataDir := filepath.Join(home, ".local", "share", "ackchyually")
+	shimDir := filepath.Join(dataDir, "shims")
+	realDir := filepath.Join(tmp, "real")
+	binDir := filepath.Join(tmp, "bin")
+
+	mkdirAll(t, shimDir)
+	mkdirAll(t, realDir)
+	mkdirAll(t, binDir)
+
+	ack := filepath.Join(binDir, "ackchyually")
+	build(t, root, "./cmd/ackchyually", ack)
+
+	// The tool prints its pid: with exec it is the shim's own process.
+	must(t, os.WriteFile(filepath.Join(realDir, "tool"), []byte("#!/bin/sh\necho \"pid=$$ args=$*\"\nexit 3\n"), 0o755))
+	must(t, os.Symlink(ack, filepath.Join(shimDir, "tool")))
+
+	cfg := filepath.Join(tmp, "config.toml")
+	must(t, os.WriteFile(cfg, []byte("[passthrough]\ntools = [\"tool\"]\n"), 0o600))
+
+	base := append(os.Environ(),
+		"HOME="+home,
+		"PATH="+strings.Join([]string{shimDir, realDir, "/usr/bin", "/bin"}, string(os.PathListSeparator)),
+		"ACKCHYUALLY_AGENT=0",
+	)
+	for _, tc := range []struct {
+		name string
+		env  []string
+	}{
+		{"env", []string{"ACKCHYUALLY_OFF=1", "ACKCHYUALLY_CONFIG=" + filepath.Join(tmp, "missing.toml")}},
+		{"config", []string{"ACKCHYUALLY_OFF=", "ACKCHYUALLY_CONFIG=" + cfg}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			cmd := exec.CommandContext(context.Background(), filepath.Join(shimDir, "tool"), "a", "b")
+			cmd.Env = append(append([]string{}, base...), tc.env...)
+			cmd.Stdout, cmd.Stderr = &out, &out
+			err := cmd.Run()
+
+			var ee *exec.ExitError
+			if !errors.As(err, &ee) || ee.ExitCode() != 3 {
+				t.Fatalf("expected exit 3, got %v:\n%s", err, out.String())
+			}
+			want := "pid=" + strconv.Itoa(cmd.Process.Pid) + " args=a b\n"
+			if out.String() != want {
+				t.Fatalf("expected the tool to replace the shim (%q), got %q", want, out.String())
+			}
+			if exists(filepath.Join(dataDir, "ackchyually.sqlite")) {
+				t.Fatalf("passthrough should not open the database")
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+//go:build !windows
+
+package integration
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// fixmeEnv is a temp HOME with a "fixme" shim whose real tool rejects
+// --verbos as a usage error and echoes everything else.
+type fixmeEnv struct {
+	ack, shim, workDir string
+	env                []string
+}
+
+func newFixmeEnv(t *testing.T, configTOML string) fixmeEnv {
+	t.Helper()
+	root := repoRoot(t)
+
+	tmp := t.TempDir()
+	home := filepath.Join(tmp, "home")
+	waitForIdentifier(t, home)
+	dataDir := filepath.Join(home, ".local", "share", "ackchyually")
+	shimDir := filepath.Join(dataDir, "shims")
+	realDir := filepath.Join(tmp, "real")
+	binDir := filepath.Join(tmp, "bin")
+	workDir := filepath.Join(tmp, "work")
+
+	mkdirAll(t, shimDir)
+	mkdirAll(t, realDir)
+	mkdirAll(t, binDir)
+	mkdirAll(t, workDir)
+
+	ack := filepath.Join(binDir, "ackchyually")
+	build(t, root, "./cmd/ackchyually", ack)
+
+	realTool := filepath.Join(realDir, "fixme")
+	must(t, os.WriteFile(realTool, []byte("#!/bin/sh\ncase \"$1\" in --verbos) echo 'unknown flag: --verbos' >&2; exit 2;; esac\necho \"RAN $*\"\n"), 0o755))
+	must(t, os.WriteFile(filepath.Join(dataDir, "config.toml"), []byte(configTOML), 0o600))
+
+	shimTool := filepath.Join(shimDir, "fixme")
+	must(t, os.Symlink(ack, shimTool))
+
+	return fixmeEnv{
+		ack:     ack,
+		shim:    shimTool,
+		workDir: workDir,
+		env: append(os.Environ(),
+			"HOME="+home,
+			"PATH="+strings.Join([]string{shimDir, realDir, "/usr/bin", "/bin"}, string(os.PathListSeparator)),
+			"ACKCHYUALLY_AGENT=0",
+		),
+	}
+}
+
+// run runs name non-interactively in the work dir.
+func (e fixmeEnv) run(t *testing.T, name string, args ...string) string {
+	t.Helper()
+	cmd := exec.CommandContext(context.Background(), name, args...)
+	cmd.Dir = e.workDir
+	cmd.Env = e.env
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s %v: %v\n%s", name, args, err, out)
+	}
+	return string(out)
+}
+
+// runPTY runs the shim on a fresh terminal and lets interact drive it.
+func (e fixmeEnv) runPTY(t *testing.T, extraEnv []string, interact func(ptmx *os.File, buf *safeBuffer), args ...string) string {
+	t.Helper()
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Fatalf("pty.Open: %v", err)
+	}
+	defer ptmx.Close()
+	defer tty.Close()
+	must(t, pty.Setsize(ptmx, &pty.Winsize{Rows: 24, Cols: 100}))
+
+	cmd := exec.CommandContext(context.Background(), e.shim, args...)
+	cmd.Dir = e.workDir
+	cmd.Env = append(append([]string{}, e.env...), extraEnv...)
+	cmd.Stdin = tty
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+
+	var buf safeBuffer
+	go func() {
+		if _, err := io.Copy(&buf, ptmx); err != nil {
+			_ = err // best-effort
+		}
+	}()
+
+	must(t, cmd.Start())
+	interact(ptmx, &buf)
+	if err := waitCmd(t, cmd, 10*time.Second); err != nil {
+		var ee *exec.ExitError
+		if !errorsAs(err, &ee) {
+			t.Fatalf("cmd failed: %v\nOUTPUT:\n%s", err, buf.String())
+		}
+	}
+	return buf.String()
+}
+
+func errorsAs(err error, ee **exec.ExitError) bool {
+	e, ok := err.(*exec.ExitError) //nolint:errorlint // cmd.Wait returns it unwrapped
+	if ok {
+		*ee = e
+	}
+	return ok
+}
+
+func press(t *testing.T, ptmx *os.File, keys string) {
+	t.Helper()
+	if _, err := ptmx.Write([]byte(keys)); err != nil {
+		t.Fatalf("ptmx.Write: %v", err)
+	}
+}
+
+func TestPTY_PickerRunsChosenSuggestion(t *testing.T) {
+	e := newFixmeEnv(t, "[suggest]\npicker = true\n")
+
+	// Record a known-good command (non-interactive, so no picker).
+	e.run(t, e.shim, "--verbose")
+
+	e.runPTY(t, nil, func(ptmx *os.File, buf *safeBuffer) {
+		waitContains(t, buf, "enter run")
+		press(t, ptmx, "\r")
+		waitContains(t, buf, "RAN --verbose")
+	}, "--verbos")
+
+	out := e.run(t, e.ack, "history", "--tool", "fixme")
+	if strings.Count(out, "fixme --verbose") != 2 {
+		t.Fatalf("picked command was not recorded via runShim:\n%s", out)
+	}
+}
+
+func TestPTY_ConfirmRunsAndPromotes(t *testing.T) {
+	e := newFixmeEnv(t, "[auto_exec]\npromote_after = 2\n")
+	confirm := []string{"ACKCHYUALLY_AUTO_EXEC=confirm"}
+
+	e.run(t, e.shim, "--verbose")
+
+	// Declining leaves the failure as is.
+	out := e.runPTY(t, confirm, func(ptmx *os.File, buf *safeBuffer) {
+		waitContains(t, buf, "[Y/n/e(dit)]")
+		press(t, ptmx, "n")
+	}, "--verbos")
+	if !strings.Contains(out, "- fixme --verbos") || !strings.Contains(out, "+ fixme --verbose") {
+		t.Fatalf("confirm prompt missing diff:\n%s", out)
+	}
+	if strings.Contains(out, "RAN") {
+		t.Fatalf("declined suggestion ran:\n%s", out)
+	}
+
+	for i := 0; i < 2; i++ {
+		e.runPTY(t, confirm, func(ptmx *os.File, buf *safeBuffer) {
+			waitContains(t, buf, "[Y/n/e(dit)]")
+			press(t, ptmx, "y")
+			waitContains(t, buf, "RAN --verbose")
+		}, "--verbos")
+	}
+
+	// Two acceptances in a row: runs without asking.
+	out = e.runPTY(t, confirm, func(ptmx *os.File, buf *safeBuffer) {
+		waitContains(t, buf, "RAN --verbose")
+	}, "--verbos")
+	if !strings.Contains(out, "auto-exec (accepted 2× before)") || strings.Contains(out, "[Y/n/e(dit)]") {
+		t.Fatalf("expected promoted auto-exec:\n%s", out)
+	}
+}
+
+func TestPTY_ConfirmEdit(t *testing.T) {
+	e := newFixmeEnv(t, "")
+	e.run(t, e.shim, "--verbose")
+
+	e.runPTY(t, []string{"ACKCHYUALLY_AUTO_EXEC=confirm"}, func(ptmx *os.File, buf *safeBuffer) {
+		waitContains(t, buf, "[Y/n/e(dit)]")
+		press(t, ptmx, "e")
+		waitContains(t, buf, "edit: fixme --verbose")
+		press(t, ptmx, " extra\r")
+		waitContains(t, buf, "RAN --verbose extra")
+	}, "--verbos")
+}
@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -25,6 +26,7 @@ func TestPTY_ShimRunsToolInPTY_AndPropagatesResize(t *testing.T) {
 
 	tmp := t.TempDir()
 	home := filepath.Join(tmp, "home")
+	waitForIdentifier(t, home)
 	shimDir := filepath.Join(home, ".local", "share", "ackchyually", "shims")
 	realDir := filepath.Join(tmp, "real")
 	binDir := filepath.Join(tmp, "bin")
@@ -106,6 +108,96 @@ func TestPTY_ShimRunsToolInPTY_AndPropagatesResize(t *testing.T) {
 	}
 }
 
+func TestPTY_CtrlZSuspendsAndFgResumes(t *testing.T) {
+	root := repoRoot(t)
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+
+	tmp := t.TempDir()
+	home := filepath.Join(tmp, "home")
+	waitForIdentifier(t, home)
+	shimDir := filepath.Join(home, ".local", "share", "ackchyually", "shims")
+	realDir := filepath.Join(tmp, "real")
+	binDir := filepath.Join(tmp, "bin")
+
+	mkdirAll(t, shimDir)
+	mkdirAll(t, realDir)
+	mkdirAll(t, binDir)
+
+	ack := filepath.Join(binDir, "ackchyually")
+	build(t, root, "./cmd/ackchyually", ack)
+	build(t, root, "./internal/testtools/promptly", filepath.Join(realDir, "promptly"))
+	shimTool := filepath.Join(shimDir, "promptly")
+	must(t, os.Symlink(ack, shimTool))
+
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Fatalf("pty.Open: %v", err)
+	}
+	defer ptmx.Close()
+	defer tty.Close()
+	must(t, pty.Setsize(ptmx, &pty.Winsize{Rows: 24, Cols: 80}))
+
+	// A job-control shell on its own terminal, as in an interactive session:
+	// the stopped job reports 128+SIGTSTP, and stty shows whether the shim
+	// handed the terminal back in cooked mode before `fg`.
+	script := shimTool + `; echo "STOPPED=$?"; ` +
+		`echo "MODE=$(stty -a | tr ' ;' '\n\n' | grep -x -e icanon -e -icanon)"; ` +
+		`fg; echo "EXIT=$?"`
+	cmd := exec.CommandContext(context.Background(), bash, "--norc", "--noprofile", "-m", "-c", script)
+	cmd.Env = append(os.Environ(),
+		"HOME="+home,
+		"PATH="+strings.Join([]string{shimDir, realDir, "/usr/bin", "/bin"}, string(os.PathListSeparator)),
+		"ACKCHYUALLY_AGENT=0",
+	)
+	cmd.Stdin = tty
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+
+	var buf safeBuffer
+	done := make(chan struct{})
+	go func() {
+		if _, err := io.Copy(&buf, ptmx); err != nil {
+			_ = err // best-effort
+		}
+		close(done)
+	}()
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	waitContains(t, &buf, "PROMPT enter y:")
+	if _, err := ptmx.Write([]byte{0x1a}); err != nil { // Ctrl-Z
+		t.Fatalf("ptmx.Write: %v", err)
+	}
+	waitContains(t, &buf, "STOPPED="+strconv.Itoa(128+int(syscall.SIGTSTP)))
+	waitContains(t, &buf, "MODE=icanon")
+
+	// Resized while suspended; the tool must see it after fg.
+	must(t, pty.Setsize(ptmx, &pty.Winsize{Rows: 30, Cols: 90}))
+	waitContains(t, &buf, "promptly") // bash echoes the job it resumes
+	time.Sleep(200 * time.Millisecond)
+	if _, err := ptmx.Write([]byte("y\r")); err != nil {
+		t.Fatalf("ptmx.Write: %v", err)
+	}
+
+	waitContains(t, &buf, "SIZE_AFTER rows=30 cols=90")
+	waitContains(t, &buf, "EXIT=0")
+	if err := waitCmd(t, cmd, 10*time.Second); err != nil {
+		t.Fatalf("cmd failed: %v\nOUTPUT:\n%s", err, buf.String())
+	}
+
+	_ = ptmx.Close()
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+	}
+}
+
 func waitForSize(t *testing.T, f *os.File, rows, cols uint16) {
 	t.Helper()
 	deadline := time.Now().Add(4 * time.Second)
@@ -126,6 +218,7 @@ func TestPipes_NonInteractiveUsesPipes(t *testing.T) {
 
 	tmp := t.TempDir()
 	home := filepath.Join(tmp, "home")
+	waitForIdentifier(t, home)
 	shimDir := filepath.Join(home, ".local", "share", "ackchyually", "shims")
 	realDir := filepath.Join(tmp, "real")
 	binDir := filepath.Join(tmp, "bin")
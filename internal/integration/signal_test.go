@@ -0,0 +1,114 @@
+//go:build !windows
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPipes_ForwardsSignalAndRecordsInterruption(t *testing.T) {
+	root := repoRoot(t)
+
+	tmp := t.TempDir()
+	home := filepath.Join(tmp, "home")
+	waitForIdentifier(t, home)
+	shimDir := filepath.Join(home, ".local", "share", "ackchyually", "shims")
+	realDir := filepath.Join(tmp, "real")
+	binDir := filepath.Join(tmp, "bin")
+	workDir := filepath.Join(tmp, "work")
+
+	mkdirAll(t, shimDir)
+	mkdirAll(t, realDir)
+	mkdirAll(t, binDir)
+	mkdirAll(t, workDir)
+
+	ack := filepath.Join(binDir, "ackchyually")
+	build(t, root, "./cmd/ackchyually", ack)
+
+	// `serve` exits 0 on SIGTERM, like a server shutting down cleanly;
+	// `work` dies from it.
+	ready := filepath.Join(tmp, "ready")
+	gotTerm := filepath.Join(tmp, "got-term")
+	realTool := filepath.Join(realDir, "slow")
+	must(t, os.WriteFile(realTool, []byte("#!/bin/sh\n"+
+		"case \"$1\" in\n"+
+		"  serve) trap 'echo TERM > \"$GOT_TERM\"; exit 0' TERM ;;\n"+
+		"  work) ;;\n"+
+		"  *) echo slow 1.0; exit 0 ;;\n"+
+		"esac\n"+
+		"echo ok > \"$READY\"\n"+
+		"while :; do sleep 0.05; done\n"), 0o755))
+
+	shimTool := filepath.Join(shimDir, "slow")
+	must(t, os.Symlink(ack, shimTool))
+
+	env := append(os.Environ(),
+		"HOME="+home,
+		"PATH="+strings.Join([]string{shimDir, realDir, "/usr/bin", "/bin"}, string(os.PathListSeparator)),
+		"ACKCHYUALLY_AGENT=0",
+		"READY="+ready,
+		"GOT_TERM="+gotTerm,
+	)
+
+	for _, tc := range []struct {
+		arg      string
+		exit     int
+		recorded string
+	}{
+		{"serve", 0, "exit=0   ok"},
+		{"work", 128 + int(syscall.SIGTERM), "exit=143 interrupted"},
+	} {
+		_ = os.Remove(ready)
+		cmd := exec.CommandContext(context.Background(), shimTool, tc.arg)
+		cmd.Dir = workDir
+		cmd.Env = env
+		// No controlling terminal, like an agent's or CI's subprocess.
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+		must(t, cmd.Start())
+
+		deadline := time.Now().Add(10 * time.Second)
+		for !exists(ready) {
+			if time.Now().After(deadline) {
+				_ = cmd.Process.Kill()
+				t.Fatalf("%s: timeout waiting for the tool to start", tc.arg)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		must(t, cmd.Process.Signal(syscall.SIGTERM))
+
+		// The shim exits as the tool did.
+		err := waitCmd(t, cmd, 10*time.Second)
+		code := 0
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			code = ee.ExitCode()
+		} else if err != nil {
+			t.Fatalf("%s: shim: %v", tc.arg, err)
+		}
+		if code != tc.exit {
+			t.Fatalf("%s: shim exit = %d, want %d", tc.arg, code, tc.exit)
+		}
+
+		hist := exec.CommandContext(context.Background(), ack, "history", "--tool", "slow", "--limit", "1")
+		hist.Dir = workDir
+		hist.Env = env
+		out, err := hist.CombinedOutput()
+		if err != nil {
+			t.Fatalf("history: %v\n%s", err, out)
+		}
+		if !strings.Contains(string(out), tc.recorded) {
+			t.Fatalf("%s: expected the run to be recorded as %q, got:\n%s", tc.arg, tc.recorded, out)
+		}
+	}
+	if !exists(gotTerm) {
+		t.Fatal("SIGTERM was not forwarded to the tool")
+	}
+}
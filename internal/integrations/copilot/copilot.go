@@ -187,6 +187,7 @@ func wrapperScript(wrapperPath, backupPath, shimDir string) []byte {
 		return []byte(fmt.Sprintf(`@echo off
 rem ackchyually copilot wrapper
 set "ACKCHYUALLY_SHIM_DIR=%s"
+if not defined ACKCHYUALLY_AGENT set "ACKCHYUALLY_AGENT=copilot"
 set "PATH=%s;%%PATH%%"
 "%s" %%*
 `, shimDir, shimDir, backupPath))
@@ -195,6 +196,7 @@ set "PATH=%s;%%PATH%%"
 	return []byte(fmt.Sprintf(`#!/bin/sh
 # ackchyually copilot wrapper
 ACKCHYUALLY_SHIM_DIR=%q
+export ACKCHYUALLY_AGENT="${ACKCHYUALLY_AGENT:-copilot}"
 export PATH="%s:$PATH"
 exec %q "$@"
 `, shimDir, shimDir, backupPath))
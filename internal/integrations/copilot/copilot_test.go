@@ -20,6 +20,7 @@ func TestWrapper_InstallRunUndo_RegularFile(t *testing.T) {
 	writeExec(t, wrapperPath, `#!/bin/sh
 echo "OUT:ORIG"
 echo "OUT:PATH=$PATH"
+echo "OUT:AGENT=$ACKCHYUALLY_AGENT"
 echo "ERR:ORIG" 1>&2
 exit 42
 `)
@@ -32,7 +33,7 @@ exit 42
 		t.Fatalf("Apply(install): %v", err)
 	}
 
-	stdout, stderr, code := run(t, wrapperPath, map[string]string{"PATH": "AAA"})
+	stdout, stderr, code := run(t, wrapperPath, map[string]string{"PATH": "AAA", "ACKCHYUALLY_AGENT": ""})
 	if code != 42 {
 		t.Fatalf("exit=%d, want 42\nSTDOUT:\n%s\nSTDERR:\n%s", code, stdout, stderr)
 	}
@@ -45,6 +46,9 @@ exit 42
 	if !strings.Contains(stdout, "OUT:PATH="+filepath.Join(tmp, "shims")+":AAA") {
 		t.Fatalf("expected PATH to be shim-first\nSTDOUT:\n%s", stdout)
 	}
+	if !strings.Contains(stdout, "OUT:AGENT=copilot") {
+		t.Fatalf("expected the wrapper to mark copilot runs\nSTDOUT:\n%s", stdout)
+	}
 
 	undo, err := PlanUndo(wrapperPath)
 	if err != nil {
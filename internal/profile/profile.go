@@ -0,0 +1,474 @@
+package profile
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joelklabo/ackchyually/internal/config"
+)
+
+// Schema:
+//
+// A profile describes how a tool reports usage errors. Profiles are JSON files
+// named after the tool (git.json, gh.json, ...). Every tool profile is layered
+// on top of default.json: lists are appended, so a tool profile only needs to
+// describe what is specific to that tool.
+//
+//   - help_flags / help_subcommands: explicit help invocations (never usage errors).
+//   - usage_exit_codes: exit codes that always mean "usage error" (64 for
+//     sysexits, 129 for git, ...).
+//   - usage_patterns: rules matched against the output of a failed invocation.
+//   - success_patterns: rules matched line-by-line against stderr of an exit=0
+//     invocation (tools that print usage errors but still exit 0).
+//   - success_exclusions: lines that are never treated as errors on exit=0
+//     (structured output such as JSON).
+//   - class_exit_codes: exit codes that imply a failure class regardless of
+//     output (e.g. 130 => interrupted, 139 => crash).
+//   - failure_patterns: rules (with a "class") matched against the output of a
+//     failed invocation that is not a usage error.
+//   - login_tokens: argv tokens that identify a login command; on auth failures
+//     the last successful one is suggested.
+//   - global_value_flags: flags that may come before the subcommand and take
+//     the next argument as their value (git -C <dir>), so that value is not
+//     taken for the subcommand.
+//
+// Matching is case-insensitive. User overrides in
+// ~/.local/share/ackchyually/profiles/<tool>.json replace the embedded file of
+// the same name.
+//
+//go:embed profiles/*.json
+var embedded embed.FS
+
+type Profile struct {
+	SchemaVersion     int      `json:"schema_version"`
+	Tool              string   `json:"tool"`
+	HelpFlags         []string `json:"help_flags,omitempty"`
+	HelpSubcommands   []string `json:"help_subcommands,omitempty"`
+	UsageExitCodes    []int    `json:"usage_exit_codes,omitempty"`
+	UsagePatterns     []Rule   `json:"usage_patterns,omitempty"`
+	SuccessPatterns   []Rule   `json:"success_patterns,omitempty"`
+	SuccessExclusions []Rule   `json:"success_exclusions,omitempty"`
+
+	ClassExitCodes  map[string][]int `json:"class_exit_codes,omitempty"`
+	FailurePatterns []Rule           `json:"failure_patterns,omitempty"`
+	LoginTokens     []string         `json:"login_tokens,omitempty"`
+
+	GlobalValueFlags []string `json:"global_value_flags,omitempty"`
+}
+
+// Failure classes stored with each invocation.
+const (
+	ClassOK          = "ok"
+	ClassUsage       = "usage"
+	ClassAuth        = "auth"
+	ClassNetwork     = "network"
+	ClassNotFound    = "not_found"
+	ClassConflict    = "conflict"
+	ClassInterrupted = "interrupted"
+	ClassTUI         = "tui" // full-screen session; its output isn't classified
+	ClassCrash       = "crash"
+	ClassError       = "error" // failed, but no more specific class matched
+)
+
+// Classes lists every class in display order.
+var Classes = []string{
+	ClassOK, ClassUsage, ClassAuth, ClassNetwork, ClassNotFound,
+	ClassConflict, ClassInterrupted, ClassTUI, ClassCrash, ClassError,
+}
+
+func isClass(s string) bool {
+	for _, c := range Classes {
+		if c == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule matches when the text starts with one of Prefix (line rules only),
+// contains every string in All, and contains at least one string in Any.
+// Empty lists are ignored.
+type Rule struct {
+	Name   string   `json:"name"`
+	Class  string   `json:"class,omitempty"`
+	Prefix []string `json:"prefix,omitempty"`
+	All    []string `json:"all,omitempty"`
+	Any    []string `json:"any,omitempty"`
+}
+
+// Match describes which rule classified an output, and on which line.
+type Match struct {
+	Rule  string
+	Class string
+	Line  string
+}
+
+// Dir holds user profile overrides.
+func Dir() string {
+	return filepath.Join(config.Dir(), "profiles")
+}
+
+// Load returns the profile for tool layered on top of the default profile.
+// Broken override files are ignored in favor of the embedded profiles.
+func Load(tool string) Profile {
+	p := Default()
+	if tool == "" || tool == "default" {
+		return p
+	}
+	if tp, ok := loadNamed(tool); ok {
+		p = merge(p, tp)
+	}
+	p.Tool = tool
+	return p
+}
+
+// Default returns the tool-independent profile.
+func Default() Profile {
+	p, ok := loadNamed("default")
+	if !ok {
+		return Profile{SchemaVersion: 1, Tool: "default"}
+	}
+	return p
+}
+
+// Names lists the embedded tool profiles (excluding default).
+func Names() []string {
+	entries, err := embedded.ReadDir("profiles")
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".json")
+		if name == "default" {
+			continue
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+func loadNamed(name string) (Profile, bool) {
+	if !isSafeName(name) {
+		return Profile{}, false
+	}
+	if b, err := os.ReadFile(filepath.Join(Dir(), name+".json")); err == nil {
+		if p, err := Parse(b); err == nil {
+			return p, true
+		}
+	}
+	b, err := embedded.ReadFile("profiles/" + name + ".json")
+	if err != nil {
+		return Profile{}, false
+	}
+	p, err := Parse(b)
+	if err != nil {
+		return Profile{}, false
+	}
+	return p, true
+}
+
+func Parse(b []byte) (Profile, error) {
+	var p Profile
+	if err := json.Unmarshal(b, &p); err != nil {
+		return Profile{}, err
+	}
+	if p.SchemaVersion != 1 {
+		return Profile{}, fmt.Errorf("profile: unsupported schema_version %d", p.SchemaVersion)
+	}
+	for _, r := range append(append(append([]Rule{}, p.UsagePatterns...), p.SuccessPatterns...), p.SuccessExclusions...) {
+		if len(r.Prefix) == 0 && len(r.All) == 0 && len(r.Any) == 0 {
+			return Profile{}, errors.New("profile: empty rule " + r.Name)
+		}
+	}
+	for _, r := range p.FailurePatterns {
+		if len(r.Prefix) == 0 && len(r.All) == 0 && len(r.Any) == 0 {
+			return Profile{}, errors.New("profile: empty rule " + r.Name)
+		}
+		if !isClass(r.Class) {
+			return Profile{}, fmt.Errorf("profile: rule %s: unknown class %q", r.Name, r.Class)
+		}
+	}
+	for c := range p.ClassExitCodes {
+		if !isClass(c) {
+			return Profile{}, fmt.Errorf("profile: class_exit_codes: unknown class %q", c)
+		}
+	}
+	return p, nil
+}
+
+func isSafeName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\`) && name != "." && name != ".."
+}
+
+func merge(base, over Profile) Profile {
+	out := base
+	out.HelpFlags = append(append([]string{}, base.HelpFlags...), over.HelpFlags...)
+	out.HelpSubcommands = append(append([]string{}, base.HelpSubcommands...), over.HelpSubcommands...)
+	out.UsageExitCodes = append(append([]int{}, base.UsageExitCodes...), over.UsageExitCodes...)
+	out.UsagePatterns = append(append([]Rule{}, base.UsagePatterns...), over.UsagePatterns...)
+	out.SuccessPatterns = append(append([]Rule{}, base.SuccessPatterns...), over.SuccessPatterns...)
+	out.SuccessExclusions = append(append([]Rule{}, base.SuccessExclusions...), over.SuccessExclusions...)
+	// Tool-specific failure patterns take precedence over the generic ones.
+	out.FailurePatterns = append(append([]Rule{}, over.FailurePatterns...), base.FailurePatterns...)
+	out.LoginTokens = append(append([]string{}, over.LoginTokens...), base.LoginTokens...)
+	out.GlobalValueFlags = append(append([]string{}, base.GlobalValueFlags...), over.GlobalValueFlags...)
+	out.ClassExitCodes = make(map[string][]int, len(base.ClassExitCodes)+len(over.ClassExitCodes))
+	for c, codes := range base.ClassExitCodes {
+		out.ClassExitCodes[c] = append([]int{}, codes...)
+	}
+	for c, codes := range over.ClassExitCodes {
+		out.ClassExitCodes[c] = append(out.ClassExitCodes[c], codes...)
+	}
+	return out
+}
+
+// IsHelpInvocation reports whether args explicitly ask for help.
+func (p Profile) IsHelpInvocation(args []string) bool {
+	if len(args) > 0 {
+		for _, s := range p.HelpSubcommands {
+			if args[0] == s {
+				return true
+			}
+		}
+	}
+	for _, a := range args {
+		for _, f := range p.HelpFlags {
+			if a == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SubcommandIndex returns the index in argv (tool name first) of the first
+// positional argument, skipping the values of global_value_flags, or -1 for
+// none.
+func (p Profile) SubcommandIndex(argv []string) int {
+	for i := 1; i < len(argv); i++ {
+		switch {
+		case p.isGlobalValueFlag(argv[i]):
+			i++
+		case argv[i] != "" && !strings.HasPrefix(argv[i], "-"):
+			return i
+		}
+	}
+	return -1
+}
+
+func (p Profile) isGlobalValueFlag(a string) bool {
+	for _, f := range p.GlobalValueFlags {
+		if a == f {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Profile) IsUsageExitCode(code int) bool {
+	for _, c := range p.UsageExitCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifyFailure classifies a failed, non-usage invocation by exit code and
+// then by failure_patterns. It falls back to ClassError.
+func (p Profile) ClassifyFailure(code int, text string) Match {
+	for _, c := range Classes {
+		for _, ec := range p.ClassExitCodes[c] {
+			if ec == code {
+				return Match{Rule: fmt.Sprintf("exit-code-%d", code), Class: c}
+			}
+		}
+	}
+	lower := strings.ToLower(text)
+	for _, r := range p.FailurePatterns {
+		if len(r.Prefix) > 0 {
+			if line, ok := matchLines(r, text); ok {
+				return Match{Rule: r.Name, Class: r.Class, Line: line}
+			}
+			continue
+		}
+		if r.matches(lower) {
+			return Match{Rule: r.Name, Class: r.Class, Line: r.firstLine(text)}
+		}
+	}
+	return Match{Class: ClassError}
+}
+
+// MatchUsage matches usage_patterns against the output of a failed invocation.
+func (p Profile) MatchUsage(text string) (Match, bool) {
+	lower := strings.ToLower(text)
+	for _, r := range p.UsagePatterns {
+		if len(r.Prefix) > 0 {
+			if line, ok := matchLines(r, text); ok {
+				return Match{Rule: r.Name, Class: ClassUsage, Line: line}, true
+			}
+			continue
+		}
+		if r.matches(lower) {
+			return Match{Rule: r.Name, Class: ClassUsage, Line: r.firstLine(text)}, true
+		}
+	}
+	return Match{}, false
+}
+
+// MatchUsageOnSuccess matches success_patterns line-by-line, skipping lines that
+// match success_exclusions. A leading "tool:" prefix on a line is also stripped.
+func (p Profile) MatchUsageOnSuccess(text string) (Match, bool) {
+	for _, line := range strings.Split(text, "\n") {
+		l := strings.TrimSpace(StripANSI(line))
+		if l == "" {
+			continue
+		}
+		if p.excluded(l) {
+			continue
+		}
+		if m, ok := p.matchSuccessLine(l); ok {
+			return m, true
+		}
+		if rest, ok := StripToolPrefix(l); ok {
+			if m, ok := p.matchSuccessLine(rest); ok {
+				m.Line = l
+				return m, true
+			}
+		}
+	}
+	return Match{}, false
+}
+
+func (p Profile) excluded(line string) bool {
+	lower := strings.ToLower(line)
+	for _, r := range p.SuccessExclusions {
+		if r.matches(lower) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Profile) matchSuccessLine(line string) (Match, bool) {
+	lower := strings.ToLower(strings.TrimSpace(line))
+	for _, r := range p.SuccessPatterns {
+		if r.matches(lower) {
+			return Match{Rule: r.Name, Class: ClassUsage, Line: line}, true
+		}
+	}
+	return Match{}, false
+}
+
+func matchLines(r Rule, text string) (string, bool) {
+	for _, line := range strings.Split(text, "\n") {
+		l := strings.TrimSpace(StripANSI(line))
+		if l != "" && r.matches(strings.ToLower(l)) {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// matches expects lower-cased input.
+func (r Rule) matches(lower string) bool {
+	if len(r.Prefix) > 0 {
+		ok := false
+		for _, s := range r.Prefix {
+			if strings.HasPrefix(lower, strings.ToLower(s)) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, s := range r.All {
+		if !strings.Contains(lower, strings.ToLower(s)) {
+			return false
+		}
+	}
+	if len(r.Any) == 0 {
+		return true
+	}
+	for _, s := range r.Any {
+		if strings.Contains(lower, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstLine returns the first output line containing one of the rule's terms.
+func (r Rule) firstLine(text string) string {
+	terms := append(append([]string{}, r.All...), r.Any...)
+	for _, line := range strings.Split(text, "\n") {
+		l := strings.ToLower(line)
+		for _, s := range terms {
+			if strings.Contains(l, strings.ToLower(s)) {
+				return strings.TrimSpace(StripANSI(line))
+			}
+		}
+	}
+	return ""
+}
+
+// StripToolPrefix removes a leading "tool:" (e.g. "curl: ...") from line.
+func StripToolPrefix(line string) (string, bool) {
+	i := strings.IndexByte(line, ':')
+	if i <= 0 {
+		return "", false
+	}
+	prefix := line[:i]
+	if strings.Contains(prefix, " ") {
+		return "", false
+	}
+	for _, r := range prefix {
+		if (r >= 'a' && r <= 'z') ||
+			(r >= 'A' && r <= 'Z') ||
+			(r >= '0' && r <= '9') ||
+			r == '_' || r == '-' || r == '.' || r == '/' {
+			continue
+		}
+		return "", false
+	}
+	rest := strings.TrimSpace(line[i+1:])
+	if rest == "" {
+		return "", false
+	}
+	return rest, true
+}
+
+func StripANSI(s string) string {
+	if s == "" {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != 0x1b {
+			out = append(out, s[i])
+			continue
+		}
+		// CSI escape sequences (e.g. "\x1b[31m", "\x1b[1;4m").
+		if i+1 < len(s) && s[i+1] == '[' {
+			i += 2
+			for i < len(s) {
+				c := s[i]
+				if (c >= '0' && c <= '9') || c == ';' {
+					i++
+					continue
+				}
+				break
+			}
+			continue
+		}
+	}
+	return string(out)
+}
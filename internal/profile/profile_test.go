@@ -0,0 +1,222 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setTempHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	return home
+}
+
+func TestEmbeddedProfilesParse(t *testing.T) {
+	setTempHome(t)
+	names := append(Names(), "default")
+	if len(names) < 2 {
+		t.Fatalf("Names() = %v, want embedded tool profiles", names)
+	}
+	for _, name := range names {
+		b, err := embedded.ReadFile("profiles/" + name + ".json")
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		p, err := Parse(b)
+		if err != nil {
+			t.Fatalf("Parse(%s): %v", name, err)
+		}
+		if p.Tool != name {
+			t.Errorf("%s.json: tool=%q, want %q", name, p.Tool, name)
+		}
+	}
+}
+
+func TestLoad_LayersToolOnDefault(t *testing.T) {
+	setTempHome(t)
+
+	git := Load("git")
+	if !git.IsUsageExitCode(129) || !git.IsUsageExitCode(64) {
+		t.Fatalf("git profile exit codes = %v, want 64 and 129", git.UsageExitCodes)
+	}
+	if Load("curl").IsUsageExitCode(129) {
+		t.Fatal("curl profile unexpectedly treats 129 as usage")
+	}
+	if _, ok := git.MatchUsage("git: 'stauts' is not a git command. See 'git --help'."); !ok {
+		t.Fatal("git profile: expected not-a-command match")
+	}
+	if _, ok := Load("unknowntool").MatchUsage("fatal: pathspec 'x' did not match any files"); ok {
+		t.Fatal("default profile: unexpected git-only pathspec match")
+	}
+}
+
+func TestLoad_UserOverrideReplacesEmbedded(t *testing.T) {
+	setTempHome(t)
+	dir := Dir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	override := `{
+  "schema_version": 1,
+  "tool": "terraform",
+  "usage_exit_codes": [2],
+  "usage_patterns": [{"name": "tf-unsupported", "any": ["unsupported argument"]}]
+}`
+	if err := os.WriteFile(filepath.Join(dir, "terraform.json"), []byte(override), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	p := Load("terraform")
+	if !p.IsUsageExitCode(2) {
+		t.Fatalf("terraform exit codes = %v, want 2", p.UsageExitCodes)
+	}
+	m, ok := p.MatchUsage("Error: Unsupported argument\n\n  on main.tf line 3")
+	if !ok || m.Rule != "tf-unsupported" || m.Line != "Error: Unsupported argument" {
+		t.Fatalf("MatchUsage = %+v, %v", m, ok)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "git.json"), []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if !Load("git").IsUsageExitCode(129) {
+		t.Fatal("broken override should fall back to embedded git profile")
+	}
+}
+
+func TestIsHelpInvocation(t *testing.T) {
+	setTempHome(t)
+	p := Default()
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"help"}, true},
+		{[]string{"status", "--help"}, true},
+		{[]string{"-h"}, true},
+		{[]string{"status"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := p.IsHelpInvocation(tt.args); got != tt.want {
+			t.Errorf("IsHelpInvocation(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestMatchUsageOnSuccess(t *testing.T) {
+	setTempHome(t)
+	p := Load("curl")
+	tests := []struct {
+		text string
+		rule string
+		want bool
+	}{
+		{"curl: unknown --write-out variable: 'fial'\n", "curl-invalid-value", true},
+		{"\x1b[31mError:\x1b[0m unknown flag: --badd\n", "cli-error", true},
+		{"usage: this is a yaml key, not a help banner\n", "", false},
+		{`{"notes":"unknown flag: --jsn"}` + "\n", "", false},
+		{`"usage": "unknown flag"` + "\n", "", false},
+		{"ERROR: previous run failed\n", "", false},
+	}
+	for _, tt := range tests {
+		m, ok := p.MatchUsageOnSuccess(tt.text)
+		if ok != tt.want || m.Rule != tt.rule {
+			t.Errorf("MatchUsageOnSuccess(%q) = %+v, %v; want rule %q, %v", tt.text, m, ok, tt.rule, tt.want)
+		}
+	}
+}
+
+func TestParse_Rejects(t *testing.T) {
+	if _, err := Parse([]byte(`{"schema_version": 2}`)); err == nil {
+		t.Error("Parse: expected error for schema_version 2")
+	}
+	if _, err := Parse([]byte(`{"schema_version": 1, "usage_patterns": [{"name": "empty"}]}`)); err == nil {
+		t.Error("Parse: expected error for empty rule")
+	}
+}
+
+func TestStripToolPrefix(t *testing.T) {
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"curl: unknown option", "unknown option", true},
+		{"no prefix here", "", false},
+		{"two words: x", "", false},
+		{"tool:", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := StripToolPrefix(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("StripToolPrefix(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestClassifyFailure(t *testing.T) {
+	setTempHome(t)
+	tests := []struct {
+		tool  string
+		code  int
+		text  string
+		class string
+	}{
+		{"tool", 130, "", ClassInterrupted},
+		{"tool", 139, "", ClassCrash},
+		{"tool", 1, "panic: runtime error: index out of range", ClassCrash},
+		{"git", 128, "remote: Invalid username or password.\nfatal: Authentication failed for 'https://x'", ClassAuth},
+		{"curl", 6, "curl: (6) Could not resolve host: example.invalid", ClassNetwork},
+		{"git", 1, "CONFLICT (content): Merge conflict in a.go\nAutomatic merge failed", ClassConflict},
+		{"cat", 1, "cat: nope.txt: No such file or directory", ClassNotFound},
+		{"gh", 4, "To get started with GitHub CLI, please run:  gh auth login", ClassAuth},
+		{"tool", 1, "something went wrong", ClassError},
+		{"tool", 1, "Error: HTTP 401: Bad credentials", ClassAuth},
+		{"tool", 1, "request failed with status code 404", ClassNotFound},
+		{"git", 128, "git@github.com: Permission denied (publickey).", ClassAuth},
+		{"go", 1, "FAIL\tpkg/foo\t0.401s", ClassError},
+		{"go", 1, "./main.go:404:2: undefined: x", ClassError},
+		{"tool", 1, "open /etc/shadow: permission denied", ClassError},
+	}
+	for _, tt := range tests {
+		m := Load(tt.tool).ClassifyFailure(tt.code, tt.text)
+		if m.Class != tt.class {
+			t.Errorf("ClassifyFailure(%s, %d, %q) = %+v, want class %q", tt.tool, tt.code, tt.text, m, tt.class)
+		}
+	}
+}
+
+func TestParse_RejectsUnknownClass(t *testing.T) {
+	if _, err := Parse([]byte(`{"schema_version": 1, "failure_patterns": [{"name": "x", "class": "bogus", "any": ["x"]}]}`)); err == nil {
+		t.Error("Parse: expected error for unknown failure class")
+	}
+	if _, err := Parse([]byte(`{"schema_version": 1, "class_exit_codes": {"bogus": [1]}}`)); err == nil {
+		t.Error("Parse: expected error for unknown class_exit_codes class")
+	}
+}
+
+func TestSubcommandIndex(t *testing.T) {
+	setTempHome(t)
+	tests := []struct {
+		tool string
+		argv []string
+		want int
+	}{
+		{"git", []string{"git", "-C", "repo", "log"}, 3},
+		{"git", []string{"git", "-c", "core.pager=cat", "--no-pager", "log"}, 4},
+		{"git", []string{"git", "--git-dir=.git", "log"}, 2},
+		{"gh", []string{"gh", "-R", "o/r", "pr", "list"}, 3},
+		// Tools without global_value_flags skip nothing: -R and -n are
+		// booleans or take a value only after the subcommand.
+		{"chmod", []string{"chmod", "-R", "755", "dir"}, 2},
+		{"grep", []string{"grep", "-n", "pat", "file"}, 2},
+		{"tool", []string{"tool", "--verbose"}, -1},
+	}
+	for _, tt := range tests {
+		if got := Load(tt.tool).SubcommandIndex(tt.argv); got != tt.want {
+			t.Errorf("SubcommandIndex(%q) = %d, want %d", tt.argv, got, tt.want)
+		}
+	}
+}
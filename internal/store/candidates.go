@@ -18,7 +18,7 @@ func (db *DB) ListSuccessCandidates(tool, ctxKey string, limit int) ([]SuccessCa
 	rows, err := db.QueryContext(context.Background(), `
 SELECT argv_json, COUNT(*) as n, MAX(created_at) as last_at
 FROM invocations
-WHERE tool = ? AND context_key = ? AND exit_code = 0
+WHERE tool = ? AND context_key = ? AND exit_code = 0 AND parent_run_id = ''
 GROUP BY argv_json
 ORDER BY last_at DESC
 LIMIT ?`, tool, ctxKey, limit)
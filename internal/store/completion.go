@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// Completion providers recorded per tool identity. "" means not checked yet.
+const (
+	CompletionCobra = "cobra"
+	CompletionNone  = "none"
+)
+
+// ToolCompletion returns the completion provider recorded for the tool binary
+// with this sha ("" if unknown or not checked yet).
+func (db *DB) ToolCompletion(sha string) (string, error) {
+	var kind string
+	err := db.QueryRowContext(context.Background(), `SELECT completion FROM tool_identities WHERE sha256 = ?`, sha).Scan(&kind)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return kind, err
+}
+
+func (db *DB) SetToolCompletion(sha, kind string) error {
+	_, err := db.ExecContext(context.Background(), `UPDATE tool_identities SET completion = ? WHERE sha256 = ?`, kind, sha)
+	return err
+}
+
+// GetCompletion returns the cached completion words for args (the argv passed
+// after `__complete`); ok is false if none are cached.
+func (db *DB) GetCompletion(sha string, args []string) (words []string, ok bool, err error) {
+	var raw string
+	err = db.QueryRowContext(context.Background(), `
+SELECT words_json FROM completions WHERE tool_sha256 = ? AND args_json = ?`, sha, MustJSON(args)).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := json.Unmarshal([]byte(raw), &words); err != nil {
+		return nil, false, nil
+	}
+	return words, true, nil
+}
+
+func (db *DB) PutCompletion(sha string, args, words []string) error {
+	_, err := db.ExecContext(context.Background(), `
+INSERT INTO completions(tool_sha256, args_json, words_json)
+VALUES (?, ?, ?)
+ON CONFLICT(tool_sha256, args_json) DO UPDATE SET
+  words_json=excluded.words_json,
+  created_at=CURRENT_TIMESTAMP`,
+		sha, MustJSON(args), MustJSON(words),
+	)
+	return err
+}
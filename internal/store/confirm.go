@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Answers recorded for ACKCHYUALLY_AUTO_EXEC=confirm prompts.
+const (
+	ChoiceYes     = "yes"
+	ChoiceNo      = "no"
+	ChoiceEdit    = "edit"
+	ChoiceTimeout = "timeout"
+	ChoiceAuto    = "auto" // ran without asking after enough acceptances
+)
+
+// ConfirmChoice is one answer to a "run this suggestion?" prompt.
+type ConfirmChoice struct {
+	At         time.Time
+	ContextKey string
+	Tool       string
+	FailedArgv []string
+	FixedArgv  []string
+	Choice     string
+}
+
+func (db *DB) RecordConfirmChoice(c ConfirmChoice) error {
+	_, err := db.ExecContext(context.Background(), `
+INSERT INTO confirm_choices(created_at, context_key, tool, failed_argv_json, fixed_argv_json, choice)
+VALUES (?, ?, ?, ?, ?, ?)`,
+		c.At.UTC().Format(time.RFC3339Nano), c.ContextKey, c.Tool, MustJSON(c.FailedArgv), MustJSON(c.FixedArgv), c.Choice,
+	)
+	return err
+}
+
+// AcceptStreak counts the most recent consecutive yes/auto answers for the
+// failed → fixed pair in ctxKey.
+func (db *DB) AcceptStreak(ctxKey, tool string, failed, fixed []string) (int, error) {
+	rows, err := db.QueryContext(context.Background(), `
+SELECT choice FROM confirm_choices
+WHERE context_key = ? AND tool = ? AND failed_argv_json = ? AND fixed_argv_json = ?
+ORDER BY created_at DESC, id DESC
+LIMIT 100`, ctxKey, tool, MustJSON(failed), MustJSON(fixed))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var choice string
+		if err := rows.Scan(&choice); err != nil {
+			return 0, err
+		}
+		if choice != ChoiceYes && choice != ChoiceAuto {
+			break
+		}
+		n++
+	}
+	return n, rows.Err()
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Correction links a usage failure to the success that followed it in the
+// same context.
+type Correction struct {
+	ContextKey string
+	Tool       string
+	Subcommand string
+	FailedArgv []string
+	FixedArgv  []string
+	Count      int
+	Last       time.Time
+}
+
+// RecordCorrection stores (or bumps) a failed → fixed argv pair.
+func (db *DB) RecordCorrection(c Correction) error {
+	_, err := db.ExecContext(context.Background(), `
+INSERT INTO corrections(context_key, tool, subcommand, failed_argv_json, fixed_argv_json, count, last_at)
+VALUES (?, ?, ?, ?, ?, 1, ?)
+ON CONFLICT(context_key, tool, failed_argv_json, fixed_argv_json)
+DO UPDATE SET count = count + 1, last_at = excluded.last_at`,
+		c.ContextKey, c.Tool, c.Subcommand, MustJSON(c.FailedArgv), MustJSON(c.FixedArgv), c.Last.UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+// LookupCorrection returns the fix most recently (then most often) applied
+// after failing with exactly failed in ctxKey.
+func (db *DB) LookupCorrection(ctxKey, tool string, failed []string) (Correction, bool, error) {
+	var fixedJSON, subcommand, lastRaw string
+	var n int
+	err := db.QueryRowContext(context.Background(), `
+SELECT subcommand, fixed_argv_json, count, last_at
+FROM corrections
+WHERE context_key = ? AND tool = ? AND failed_argv_json = ?
+ORDER BY last_at DESC, count DESC
+LIMIT 1`, ctxKey, tool, MustJSON(failed)).Scan(&subcommand, &fixedJSON, &n, &lastRaw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Correction{}, false, nil
+	}
+	if err != nil {
+		return Correction{}, false, err
+	}
+	var fixed []string
+	if err := json.Unmarshal([]byte(fixedJSON), &fixed); err != nil || len(fixed) == 0 {
+		return Correction{}, false, nil
+	}
+	return Correction{
+		ContextKey: ctxKey,
+		Tool:       tool,
+		Subcommand: subcommand,
+		FailedArgv: failed,
+		FixedArgv:  fixed,
+		Count:      n,
+		Last:       parseDBTime(lastRaw),
+	}, true, nil
+}
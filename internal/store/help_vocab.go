@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// HelpVocab caches the subcommands and flags parsed from `<tool> [sub] --help`
+// for one tool binary (by SHA-256, see tool_identities). Subcommand is "" for
+// the top-level help. An empty vocabulary is cached too so tools without
+// parseable help are not re-run.
+type HelpVocab struct {
+	ToolSHA     string
+	Subcommand  string
+	Subcommands []string
+	Flags       []string
+}
+
+func (db *DB) PutHelpVocab(v HelpVocab) error {
+	_, err := db.ExecContext(context.Background(), `
+INSERT INTO help_vocab(tool_sha256, subcommand, subcommands_json, flags_json)
+VALUES (?, ?, ?, ?)
+ON CONFLICT(tool_sha256, subcommand) DO UPDATE SET
+  subcommands_json=excluded.subcommands_json,
+  flags_json=excluded.flags_json,
+  created_at=CURRENT_TIMESTAMP`,
+		v.ToolSHA, v.Subcommand, MustJSON(v.Subcommands), MustJSON(v.Flags),
+	)
+	return err
+}
+
+// GetHelpVocab returns the cached vocabulary; ok is false if none is cached.
+func (db *DB) GetHelpVocab(sha, sub string) (HelpVocab, bool, error) {
+	var subsJSON, flagsJSON string
+	err := db.QueryRowContext(context.Background(), `
+SELECT subcommands_json, flags_json FROM help_vocab
+WHERE tool_sha256 = ? AND subcommand = ?`, sha, sub).Scan(&subsJSON, &flagsJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return HelpVocab{}, false, nil
+	}
+	if err != nil {
+		return HelpVocab{}, false, err
+	}
+	v := HelpVocab{ToolSHA: sha, Subcommand: sub}
+	if err := json.Unmarshal([]byte(subsJSON), &v.Subcommands); err != nil {
+		return HelpVocab{}, false, nil
+	}
+	if err := json.Unmarshal([]byte(flagsJSON), &v.Flags); err != nil {
+		return HelpVocab{}, false, nil
+	}
+	return v, true, nil
+}
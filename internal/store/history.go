@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+const invocationColumns = `id, created_at, duration_ms, context_key, tool, exe_path, tool_id, argv_json, exit_code, mode, stdout_tail, stderr_tail, combined_tail, class, agent, signal,
+cpu_user_ms, cpu_sys_ms, max_rss_kb, stdout_bytes, stderr_bytes, run_id, parent_run_id`
+
+// ListInvocations returns the most recent invocations in ctxKey, newest first.
+// An empty tool matches every tool.
+func (db *DB) ListInvocations(ctxKey, tool string, limit int) ([]Invocation, error) {
+	var rows *sql.Rows
+	var err error
+	if tool == "" {
+		rows, err = db.QueryContext(context.Background(), `
+SELECT `+invocationColumns+`
+FROM invocations
+WHERE context_key = ?
+ORDER BY created_at DESC, id DESC
+LIMIT ?`, ctxKey, limit)
+	} else {
+		rows, err = db.QueryContext(context.Background(), `
+SELECT `+invocationColumns+`
+FROM invocations
+WHERE context_key = ? AND tool = ?
+ORDER BY created_at DESC, id DESC
+LIMIT ?`, ctxKey, tool, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Invocation
+	for rows.Next() {
+		inv, err := scanInvocation(rows)
+		if err != nil {
+			continue
+		}
+		out = append(out, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListInvocationTrees returns the most recent top-level invocations in ctxKey
+// (those not run by another recorded invocation) and, in any context, every
+// invocation nested under them, oldest first. An empty tool matches every
+// top-level tool.
+func (db *DB) ListInvocationTrees(ctxKey, tool string, limit int) ([]Invocation, error) {
+	rows, err := db.QueryContext(context.Background(), `
+WITH RECURSIVE roots AS (
+  SELECT r.id FROM invocations r
+  WHERE r.context_key = ? AND (? = '' OR r.tool = ?)
+    AND (r.parent_run_id = '' OR NOT EXISTS (SELECT 1 FROM invocations p WHERE p.run_id = r.parent_run_id))
+  ORDER BY r.created_at DESC, r.id DESC
+  LIMIT ?
+), tree(id, run_id) AS (
+  SELECT id, run_id FROM invocations WHERE id IN (SELECT id FROM roots)
+  UNION
+  SELECT c.id, c.run_id FROM invocations c JOIN tree t ON t.run_id != '' AND c.parent_run_id = t.run_id
+)
+SELECT `+invocationColumns+`
+FROM invocations
+WHERE id IN (SELECT id FROM tree)
+ORDER BY created_at ASC, id ASC`, ctxKey, tool, tool, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Invocation
+	for rows.Next() {
+		inv, err := scanInvocation(rows)
+		if err != nil {
+			continue
+		}
+		out = append(out, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetInvocation returns a single invocation by id.
+func (db *DB) GetInvocation(id int64) (Invocation, error) {
+	return scanInvocation(db.QueryRowContext(context.Background(), `
+SELECT `+invocationColumns+`
+FROM invocations
+WHERE id = ?`, id))
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanInvocation(r rowScanner) (Invocation, error) {
+	var inv Invocation
+	var at string
+	var toolID sql.NullInt64
+	if err := r.Scan(&inv.ID, &at, &inv.DurationMS, &inv.ContextKey, &inv.Tool, &inv.ExePath, &toolID,
+		&inv.ArgvJSON, &inv.ExitCode, &inv.Mode, &inv.StdoutTail, &inv.StderrTail, &inv.CombinedTail, &inv.Class, &inv.Agent, &inv.Signal,
+		&inv.CPUUserMS, &inv.CPUSysMS, &inv.MaxRSSKB, &inv.StdoutBytes, &inv.StderrBytes, &inv.RunID, &inv.ParentRunID); err != nil {
+		return Invocation{}, err
+	}
+	inv.At = parseDBTime(at)
+	inv.ToolID = toolID.Int64
+	return inv, nil
+}
+
+type ClassCount struct {
+	Tool  string
+	Agent string
+	Class string
+	Count int
+}
+
+// ClassCounts returns invocation counts per tool, agent and failure class in
+// ctxKey. An empty ctxKey counts across all contexts.
+func (db *DB) ClassCounts(ctxKey string) ([]ClassCount, error) {
+	rows, err := db.QueryContext(context.Background(), `
+SELECT tool, agent, class, COUNT(*)
+FROM invocations
+WHERE (? = '' OR context_key = ?)
+GROUP BY tool, agent, class
+ORDER BY tool ASC, agent ASC, class ASC`, ctxKey, ctxKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ClassCount
+	for rows.Next() {
+		var c ClassCount
+		if err := rows.Scan(&c.Tool, &c.Agent, &c.Class, &c.Count); err != nil {
+			continue
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// identifyClaimTTL is how long a queued binary is left to the identifier
+// that claimed it before another shim may start one for it again.
+const identifyClaimTTL = 10 * time.Minute
+
+// FileStamp is a file's size and modification time: enough to tell one
+// binary at a path from the next, as tool_path_cache does.
+type FileStamp struct {
+	Size    int64
+	MtimeNS int64
+}
+
+// QueueToolIdentify queues exe, as stamped when invocationID ran it, for
+// background identification. It reports whether the caller should start an
+// identifier: false if one was started recently for this same binary.
+func (db *DB) QueueToolIdentify(exe string, stamp FileStamp, invocationID int64, now time.Time) (bool, error) {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(context.Background(), `
+INSERT OR REPLACE INTO tool_identify_pending(invocation_id, exe_path, file_size, file_mtime_ns) VALUES (?, ?, ?, ?)`,
+		invocationID, exe, stamp.Size, stamp.MtimeNS); err != nil {
+		return false, err
+	}
+	res, err := tx.ExecContext(context.Background(), `
+INSERT INTO tool_identify_queue(exe_path, queued_at, file_size, file_mtime_ns) VALUES (?, ?, ?, ?)
+ON CONFLICT(exe_path) DO UPDATE SET queued_at = excluded.queued_at, file_size = excluded.file_size, file_mtime_ns = excluded.file_mtime_ns
+WHERE queued_at < ? OR file_size != excluded.file_size OR file_mtime_ns != excluded.file_mtime_ns`,
+		exe, now.Unix(), stamp.Size, stamp.MtimeNS, now.Add(-identifyClaimTTL).Unix())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, tx.Commit()
+}
+
+// QueuedTools returns the executables waiting to be identified, oldest first.
+func (db *DB) QueuedTools() ([]string, error) {
+	rows, err := db.QueryContext(context.Background(), `SELECT exe_path FROM tool_identify_queue ORDER BY queued_at ASC, exe_path ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var exe string
+		if err := rows.Scan(&exe); err != nil {
+			return nil, err
+		}
+		out = append(out, exe)
+	}
+	return out, rows.Err()
+}
+
+// FinishToolIdentify records that the binary at exe, stamped at since, is
+// tool toolID (0: it could not be identified). Only the invocations queued
+// against that same stamp get toolID. Those queued against another stamp ran
+// a binary that was replaced before since and stay without a tool, unless
+// that binary was queued at or after since: it may be newer, and is left to
+// the identifier started for it.
+func (db *DB) FinishToolIdentify(exe string, stamp FileStamp, since time.Time, toolID int64) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if toolID != 0 {
+		if _, err := tx.ExecContext(context.Background(), `
+UPDATE invocations SET tool_id = ?
+WHERE tool_id IS NULL AND id IN (
+  SELECT invocation_id FROM tool_identify_pending
+  WHERE exe_path = ? AND file_size = ? AND file_mtime_ns = ?)`, toolID, exe, stamp.Size, stamp.MtimeNS); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(context.Background(), `
+DELETE FROM tool_identify_queue
+WHERE exe_path = ? AND ((file_size = ? AND file_mtime_ns = ?) OR queued_at < ?)`,
+		exe, stamp.Size, stamp.MtimeNS, since.Unix()); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(context.Background(), `
+DELETE FROM tool_identify_pending
+WHERE exe_path = ? AND NOT EXISTS (
+  SELECT 1 FROM tool_identify_queue q
+  WHERE q.exe_path = tool_identify_pending.exe_path
+    AND q.file_size = tool_identify_pending.file_size
+    AND q.file_mtime_ns = tool_identify_pending.file_mtime_ns)`, exe); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Runs that say nothing about how fast a command is: cut short by a signal,
+// or a full-screen session that lasted as long as someone kept it open.
+const perfExcludedClasses = `('interrupted', 'tui')`
+
+// CommandTiming summarizes the runs of one command line.
+type CommandTiming struct {
+	Tool     string
+	ArgvJSON string
+	Runs     int
+	AvgMS    int64
+	MaxMS    int64
+	AvgCPUMS int64 // user + sys
+	MaxRSSKB int64
+}
+
+// SlowestCommands returns the command lines in ctxKey with the highest
+// average duration, slowest first. An empty ctxKey covers all contexts and an
+// empty tool every tool.
+func (db *DB) SlowestCommands(ctxKey, tool string, limit int) ([]CommandTiming, error) {
+	rows, err := db.QueryContext(context.Background(), `
+SELECT tool, argv_json, COUNT(*), CAST(AVG(duration_ms) AS INTEGER), MAX(duration_ms),
+  CAST(AVG(cpu_user_ms + cpu_sys_ms) AS INTEGER), MAX(max_rss_kb)
+FROM invocations
+WHERE (? = '' OR context_key = ?) AND (? = '' OR tool = ?)
+  AND class NOT IN `+perfExcludedClasses+`
+GROUP BY tool, argv_json
+ORDER BY 4 DESC, 3 DESC
+LIMIT ?`, ctxKey, ctxKey, tool, tool, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CommandTiming
+	for rows.Next() {
+		var c CommandTiming
+		if err := rows.Scan(&c.Tool, &c.ArgvJSON, &c.Runs, &c.AvgMS, &c.MaxMS, &c.AvgCPUMS, &c.MaxRSSKB); err != nil {
+			continue
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VersionTiming is the average duration of one command line under one tool
+// binary.
+type VersionTiming struct {
+	Tool     string
+	ArgvJSON string
+	ToolID   int64
+	Version  string // tool_identities.version_str
+	SemVer   string // tool_identities.version
+	Runs     int
+	AvgMS    int64
+}
+
+// TimingsByToolVersion returns the successful runs in ctxKey grouped by
+// command line and tool binary, each command's binaries in the order they
+// were first used. Runs without a tool identity are left out.
+func (db *DB) TimingsByToolVersion(ctxKey, tool string) ([]VersionTiming, error) {
+	rows, err := db.QueryContext(context.Background(), `
+SELECT i.tool, i.argv_json, i.tool_id, t.version_str, t.version, COUNT(*), CAST(AVG(i.duration_ms) AS INTEGER)
+FROM invocations i
+LEFT JOIN tool_identities t ON t.id = i.tool_id
+WHERE (? = '' OR i.context_key = ?) AND (? = '' OR i.tool = ?)
+  AND i.tool_id IS NOT NULL AND i.exit_code = 0
+  AND i.class NOT IN `+perfExcludedClasses+`
+GROUP BY i.tool, i.argv_json, i.tool_id
+ORDER BY i.tool ASC, i.argv_json ASC, MIN(i.created_at) ASC`, ctxKey, ctxKey, tool, tool)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []VersionTiming
+	for rows.Next() {
+		var v VersionTiming
+		var version, semver sql.NullString
+		if err := rows.Scan(&v.Tool, &v.ArgvJSON, &v.ToolID, &version, &semver, &v.Runs, &v.AvgMS); err != nil {
+			continue
+		}
+		v.Version, v.SemVer = version.String, semver.String
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VersionOutcome counts the runs of one command line under one parsed tool
+// version.
+type VersionOutcome struct {
+	Tool     string
+	ArgvJSON string
+	Version  string // canonical semver, tool_identities.version
+	OK       int
+	Failed   int
+}
+
+// OutcomesByToolVersion returns how each command line in ctxKey fared under
+// each tool version. Runs of tools whose version couldn't be parsed, nested
+// calls and runs that say nothing about the command are left out.
+func (db *DB) OutcomesByToolVersion(ctxKey, tool string) ([]VersionOutcome, error) {
+	rows, err := db.QueryContext(context.Background(), `
+SELECT i.tool, i.argv_json, t.version,
+  SUM(CASE WHEN i.exit_code = 0 THEN 1 ELSE 0 END), SUM(CASE WHEN i.exit_code != 0 THEN 1 ELSE 0 END)
+FROM invocations i
+JOIN tool_identities t ON t.id = i.tool_id
+WHERE (? = '' OR i.context_key = ?) AND (? = '' OR i.tool = ?)
+  AND t.version != '' AND i.parent_run_id = ''
+  AND i.class NOT IN `+perfExcludedClasses+`
+GROUP BY i.tool, i.argv_json, t.version
+ORDER BY i.tool ASC, i.argv_json ASC`, ctxKey, ctxKey, tool, tool)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []VersionOutcome
+	for rows.Next() {
+		var v VersionOutcome
+		if err := rows.Scan(&v.Tool, &v.ArgvJSON, &v.Version, &v.OK, &v.Failed); err != nil {
+			continue
+		}
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
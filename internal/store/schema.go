@@ -1,12 +1,16 @@
 package store
 
+import "database/sql"
+
 const schema = `
 CREATE TABLE IF NOT EXISTS tool_identities (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   exe_path TEXT NOT NULL,
   sha256 TEXT NOT NULL UNIQUE,
   version_str TEXT NOT NULL,
-  created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  completion TEXT NOT NULL DEFAULT '',
+  version TEXT NOT NULL DEFAULT ''
 );
 
 CREATE TABLE IF NOT EXISTS tool_path_cache (
@@ -30,7 +34,17 @@ CREATE TABLE IF NOT EXISTS invocations (
   mode TEXT NOT NULL,
   stdout_tail TEXT NOT NULL,
   stderr_tail TEXT NOT NULL,
-  combined_tail TEXT NOT NULL
+  combined_tail TEXT NOT NULL,
+  class TEXT NOT NULL DEFAULT '',
+  agent TEXT NOT NULL DEFAULT '',
+  signal TEXT NOT NULL DEFAULT '',
+  cpu_user_ms INTEGER NOT NULL DEFAULT 0,
+  cpu_sys_ms INTEGER NOT NULL DEFAULT 0,
+  max_rss_kb INTEGER NOT NULL DEFAULT 0,
+  stdout_bytes INTEGER NOT NULL DEFAULT 0,
+  stderr_bytes INTEGER NOT NULL DEFAULT 0,
+  run_id TEXT NOT NULL DEFAULT '',
+  parent_run_id TEXT NOT NULL DEFAULT ''
 );
 
 CREATE INDEX IF NOT EXISTS invocations_lookup
@@ -45,4 +59,106 @@ CREATE TABLE IF NOT EXISTS tags (
   argv_json TEXT NOT NULL,
   UNIQUE(context_key, tag)
 );
+
+CREATE TABLE IF NOT EXISTS corrections (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  context_key TEXT NOT NULL,
+  tool TEXT NOT NULL,
+  subcommand TEXT NOT NULL,
+  failed_argv_json TEXT NOT NULL,
+  fixed_argv_json TEXT NOT NULL,
+  count INTEGER NOT NULL DEFAULT 1,
+  last_at DATETIME NOT NULL,
+  UNIQUE(context_key, tool, failed_argv_json, fixed_argv_json)
+);
+
+CREATE TABLE IF NOT EXISTS confirm_choices (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at DATETIME NOT NULL,
+  context_key TEXT NOT NULL,
+  tool TEXT NOT NULL,
+  failed_argv_json TEXT NOT NULL,
+  fixed_argv_json TEXT NOT NULL,
+  choice TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS confirm_choices_lookup
+  ON confirm_choices(context_key, tool, failed_argv_json, fixed_argv_json, created_at);
+
+CREATE TABLE IF NOT EXISTS help_vocab (
+  tool_sha256 TEXT NOT NULL,
+  subcommand TEXT NOT NULL,
+  subcommands_json TEXT NOT NULL,
+  flags_json TEXT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY(tool_sha256, subcommand)
+);
+
+CREATE TABLE IF NOT EXISTS completions (
+  tool_sha256 TEXT NOT NULL,
+  args_json TEXT NOT NULL,
+  words_json TEXT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY(tool_sha256, args_json)
+);
+
+CREATE TABLE IF NOT EXISTS tool_identify_queue (
+  exe_path TEXT PRIMARY KEY,
+  queued_at INTEGER NOT NULL,
+  file_size INTEGER NOT NULL DEFAULT 0,
+  file_mtime_ns INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS tool_identify_pending (
+  invocation_id INTEGER PRIMARY KEY,
+  exe_path TEXT NOT NULL,
+  file_size INTEGER NOT NULL,
+  file_mtime_ns INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS vocab_probe_queue (
+  exe_path TEXT NOT NULL,
+  argv_json TEXT NOT NULL,
+  queued_at INTEGER NOT NULL,
+  PRIMARY KEY(exe_path, argv_json)
+);
+`
+
+// columnMigrations add columns introduced after a table was first created.
+// Fresh databases already have them from schema; older ones get an ALTER TABLE.
+var columnMigrations = []struct {
+	table, column, decl string
+}{
+	{"invocations", "class", "TEXT NOT NULL DEFAULT ''"},
+	{"tool_identities", "completion", "TEXT NOT NULL DEFAULT ''"},
+	{"invocations", "agent", "TEXT NOT NULL DEFAULT ''"},
+	{"invocations", "signal", "TEXT NOT NULL DEFAULT ''"},
+	{"invocations", "cpu_user_ms", "INTEGER NOT NULL DEFAULT 0"},
+	{"invocations", "cpu_sys_ms", "INTEGER NOT NULL DEFAULT 0"},
+	{"invocations", "max_rss_kb", "INTEGER NOT NULL DEFAULT 0"},
+	{"invocations", "stdout_bytes", "INTEGER NOT NULL DEFAULT 0"},
+	{"invocations", "stderr_bytes", "INTEGER NOT NULL DEFAULT 0"},
+	{"invocations", "run_id", "TEXT NOT NULL DEFAULT ''"},
+	{"invocations", "parent_run_id", "TEXT NOT NULL DEFAULT ''"},
+	{"tool_identities", "version", "TEXT NOT NULL DEFAULT ''"},
+	{"tool_identify_queue", "file_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"tool_identify_queue", "file_mtime_ns", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// columnFills derive a migrated column for the rows that existed before it,
+// keyed by "table.column".
+var columnFills = map[string]func(*sql.DB) error{
+	"tool_identities.version": fillToolVersions,
+}
+
+// postMigrationSchema may reference migrated columns.
+const postMigrationSchema = `
+CREATE INDEX IF NOT EXISTS invocations_class
+  ON invocations(context_key, tool, class);
+
+CREATE INDEX IF NOT EXISTS invocations_run
+  ON invocations(run_id);
+
+CREATE INDEX IF NOT EXISTS invocations_parent
+  ON invocations(parent_run_id);
 `
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite" // register sqlite driver
@@ -14,6 +15,7 @@ import (
 type DB struct{ *sql.DB }
 
 type Invocation struct {
+	ID           int64 // set when read back; ignored by InsertInvocation
 	At           time.Time
 	DurationMS   int64
 	ContextKey   string
@@ -26,13 +28,29 @@ type Invocation struct {
 	StdoutTail   string
 	StderrTail   string
 	CombinedTail string
+	Class        string // failure class (see profile.Classes); empty for old rows
+	Agent        string // "human" or the coding agent that ran it; empty for old rows
+	Signal       string // signal that ended the run ("SIGINT"), if any
+	// Resource usage; all 0 for old rows (and MaxRSSKB on Windows).
+	CPUUserMS   int64
+	CPUSysMS    int64
+	MaxRSSKB    int64
+	StdoutBytes int64
+	StderrBytes int64
+	// Lineage (see ACKCHYUALLY_PARENT_ID): RunID is set when nested calls are
+	// recorded, ParentRunID when this run is one of them.
+	RunID       string
+	ParentRunID string
 }
 
 type ToolIdentity struct {
 	ID         int64
 	ExePath    string
 	SHA256     string
-	VersionStr string
+	VersionStr string // the tool's basename, then what it printed for --version
+	// Version is the canonical semver parsed from VersionStr ("v2.44.0"), or
+	// "" if it has none. UpsertTool fills it in.
+	Version string
 }
 
 type Tag struct {
@@ -70,7 +88,9 @@ func Open() (*DB, error) {
 	if err := os.MkdirAll(dataDir(), 0o755); err != nil {
 		return nil, err
 	}
-	db, err := sql.Open("sqlite", dbPath())
+	// Shims and the background tool identifier write concurrently: wait for
+	// the lock instead of failing with SQLITE_BUSY.
+	db, err := sql.Open("sqlite", dbPath()+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, err
 	}
@@ -84,9 +104,62 @@ func Open() (*DB, error) {
 		_ = db.Close()
 		return nil, err
 	}
+	if err := migrate(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 	return &DB{db}, nil
 }
 
+func migrate(db *sql.DB) error {
+	for _, m := range columnMigrations {
+		ok, err := hasColumn(db, m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		if _, err := db.ExecContext(context.Background(), `ALTER TABLE `+m.table+` ADD COLUMN `+m.column+` `+m.decl); err != nil {
+			if isDuplicateColumn(err) {
+				// Another shim (or the background identifier) opening the
+				// same old database added it since the check; it fills it.
+				continue
+			}
+			return err
+		}
+		if fill, ok := columnFills[m.table+"."+m.column]; ok {
+			if err := fill(db); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := db.ExecContext(context.Background(), postMigrationSchema)
+	return err
+}
+
+func isDuplicateColumn(err error) bool {
+	return strings.Contains(err.Error(), "duplicate column name")
+}
+
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.QueryContext(context.Background(), `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
 func WithDB(fn func(*DB) error) error {
 	db, err := Open()
 	if err != nil {
@@ -97,19 +170,30 @@ func WithDB(fn func(*DB) error) error {
 }
 
 func (db *DB) InsertInvocation(inv Invocation) error {
-	_, err := db.ExecContext(context.Background(), `
+	_, err := db.InsertInvocationID(inv)
+	return err
+}
+
+// InsertInvocationID is InsertInvocation returning the new row's id.
+func (db *DB) InsertInvocationID(inv Invocation) (int64, error) {
+	res, err := db.ExecContext(context.Background(), `
 INSERT INTO invocations
-(created_at, duration_ms, context_key, tool, exe_path, tool_id, argv_json, exit_code, mode, stdout_tail, stderr_tail, combined_tail)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+(created_at, duration_ms, context_key, tool, exe_path, tool_id, argv_json, exit_code, mode, stdout_tail, stderr_tail, combined_tail, class, agent, signal,
+ cpu_user_ms, cpu_sys_ms, max_rss_kb, stdout_bytes, stderr_bytes, run_id, parent_run_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		inv.At.UTC().Format(time.RFC3339Nano), inv.DurationMS, inv.ContextKey, inv.Tool, inv.ExePath, nullIfZero(inv.ToolID),
-		inv.ArgvJSON, inv.ExitCode, inv.Mode, inv.StdoutTail, inv.StderrTail, inv.CombinedTail,
+		inv.ArgvJSON, inv.ExitCode, inv.Mode, inv.StdoutTail, inv.StderrTail, inv.CombinedTail, inv.Class, inv.Agent, inv.Signal,
+		inv.CPUUserMS, inv.CPUSysMS, inv.MaxRSSKB, inv.StdoutBytes, inv.StderrBytes, inv.RunID, inv.ParentRunID,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
 }
 
 func (db *DB) UpsertTool(t ToolIdentity) (int64, error) {
-	if _, err := db.ExecContext(context.Background(), `INSERT OR IGNORE INTO tool_identities(exe_path, sha256, version_str) VALUES (?, ?, ?)`,
-		t.ExePath, t.SHA256, t.VersionStr,
+	if _, err := db.ExecContext(context.Background(), `INSERT OR IGNORE INTO tool_identities(exe_path, sha256, version_str, version) VALUES (?, ?, ?, ?)`,
+		t.ExePath, t.SHA256, t.VersionStr, parseToolVersion(t.ExePath, t.VersionStr),
 	); err != nil {
 		return 0, err
 	}
@@ -120,15 +204,22 @@ func (db *DB) UpsertTool(t ToolIdentity) (int64, error) {
 
 func (db *DB) GetToolBySHA(sha string) (ToolIdentity, error) {
 	var t ToolIdentity
-	err := db.QueryRowContext(context.Background(), `SELECT id, exe_path, sha256, version_str FROM tool_identities WHERE sha256 = ?`, sha).
-		Scan(&t.ID, &t.ExePath, &t.SHA256, &t.VersionStr)
+	err := db.QueryRowContext(context.Background(), `SELECT id, exe_path, sha256, version_str, version FROM tool_identities WHERE sha256 = ?`, sha).
+		Scan(&t.ID, &t.ExePath, &t.SHA256, &t.VersionStr, &t.Version)
+	return t, err
+}
+
+func (db *DB) GetToolByID(id int64) (ToolIdentity, error) {
+	var t ToolIdentity
+	err := db.QueryRowContext(context.Background(), `SELECT id, exe_path, sha256, version_str, version FROM tool_identities WHERE id = ?`, id).
+		Scan(&t.ID, &t.ExePath, &t.SHA256, &t.VersionStr, &t.Version)
 	return t, err
 }
 
 func (db *DB) ListSuccessful(tool, ctxKey string, limit int) ([][]string, error) {
 	rows, err := db.QueryContext(context.Background(), `
 SELECT argv_json FROM invocations
-WHERE tool = ? AND context_key = ? AND exit_code = 0
+WHERE tool = ? AND context_key = ? AND exit_code = 0 AND parent_run_id = ''
 ORDER BY created_at DESC
 LIMIT ?`, tool, ctxKey, limit)
 	if err != nil {
@@ -2,8 +2,12 @@ package store
 
 import (
 	"context"
+	"database/sql"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -427,3 +431,432 @@ func equalStringSlices(a, b []string) bool {
 	}
 	return true
 }
+
+func TestListInvocations_FiltersAndOrders(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+	for i, inv := range []Invocation{
+		{At: now.Add(-2 * time.Minute), ContextKey: "ctx", Tool: "git", ArgvJSON: `["git","status"]`, ExitCode: 0, Mode: "pipes"},
+		{At: now.Add(-1 * time.Minute), ContextKey: "ctx", Tool: "gh", ArgvJSON: `["gh","pr","list"]`, ExitCode: 1, Mode: "pipes"},
+		{At: now, ContextKey: "other", Tool: "git", ArgvJSON: `["git","log"]`, ExitCode: 0, Mode: "pipes"},
+	} {
+		if err := db.InsertInvocation(inv); err != nil {
+			t.Fatalf("InsertInvocation[%d]: %v", i, err)
+		}
+	}
+
+	all, err := db.ListInvocations("ctx", "", 10)
+	if err != nil {
+		t.Fatalf("ListInvocations: %v", err)
+	}
+	if len(all) != 2 || all[0].Tool != "gh" || all[1].Tool != "git" {
+		t.Fatalf("ListInvocations(ctx) = %+v", all)
+	}
+	if all[0].ID == 0 || all[0].At.IsZero() {
+		t.Fatalf("ListInvocations: missing ID/At: %+v", all[0])
+	}
+
+	git, err := db.ListInvocations("ctx", "git", 10)
+	if err != nil {
+		t.Fatalf("ListInvocations(git): %v", err)
+	}
+	if len(git) != 1 || git[0].ArgvJSON != `["git","status"]` {
+		t.Fatalf("ListInvocations(ctx, git) = %+v", git)
+	}
+}
+
+func TestOpen_MigratesOldInvocationsTable(t *testing.T) {
+	setTempHome(t)
+	if err := os.MkdirAll(dataDir(), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	raw, err := sql.Open("sqlite", dbPath())
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	if _, err := raw.ExecContext(context.Background(), `
+CREATE TABLE invocations (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at DATETIME NOT NULL,
+  duration_ms INTEGER NOT NULL,
+  context_key TEXT NOT NULL,
+  tool TEXT NOT NULL,
+  exe_path TEXT NOT NULL,
+  tool_id INTEGER,
+  argv_json TEXT NOT NULL,
+  exit_code INTEGER NOT NULL,
+  mode TEXT NOT NULL,
+  stdout_tail TEXT NOT NULL,
+  stderr_tail TEXT NOT NULL,
+  combined_tail TEXT NOT NULL
+);
+CREATE TABLE tool_identities (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  exe_path TEXT NOT NULL,
+  sha256 TEXT NOT NULL UNIQUE,
+  version_str TEXT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO tool_identities(exe_path, sha256, version_str) VALUES ('/usr/bin/gh', 'ghsha', 'gh 2.0');
+INSERT INTO invocations(created_at, duration_ms, context_key, tool, exe_path, argv_json, exit_code, mode, stdout_tail, stderr_tail, combined_tail)
+VALUES ('2025-01-01T00:00:00Z', 1, 'ctx', 'git', '/usr/bin/git', '["git","status"]', 0, 'pipes', '', '', '');`); err != nil {
+		t.Fatalf("create old schema: %v", err)
+	}
+	_ = raw.Close()
+
+	db, err := Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.InsertInvocation(Invocation{At: time.Now(), ContextKey: "ctx", Tool: "git", ArgvJSON: `["git","log"]`, ExitCode: 129, Mode: "pipes", Class: "usage", Agent: "codex"}); err != nil {
+		t.Fatalf("InsertInvocation after migration: %v", err)
+	}
+	counts, err := db.ClassCounts("ctx")
+	if err != nil {
+		t.Fatalf("ClassCounts: %v", err)
+	}
+	got := map[string]int{}
+	for _, c := range counts {
+		got[c.Agent+"/"+c.Class] += c.Count
+	}
+	if got["/"] != 1 || got["codex/usage"] != 1 {
+		t.Fatalf("ClassCounts = %+v", counts)
+	}
+
+	if err := db.SetToolCompletion("ghsha", CompletionCobra); err != nil {
+		t.Fatalf("SetToolCompletion after migration: %v", err)
+	}
+	if kind, err := db.ToolCompletion("ghsha"); err != nil || kind != CompletionCobra {
+		t.Fatalf("ToolCompletion = %q, %v", kind, err)
+	}
+	if gh, err := db.GetToolBySHA("ghsha"); err != nil || gh.Version != "v2.0.0" {
+		t.Fatalf("version parsed for an existing tool = %q, %v", gh.Version, err)
+	}
+}
+
+func TestOpen_ColumnAddedByAnotherOpener(t *testing.T) {
+	setTempHome(t)
+	// SQLite column names are case-insensitive, but hasColumn's check isn't:
+	// the ALTER then fails as it does for an opener that lost the race to
+	// another one after checking.
+	orig := columnMigrations
+	columnMigrations = append(columnMigrations, struct{ table, column, decl string }{"tool_identities", "COMPLETION", "TEXT NOT NULL DEFAULT ''"})
+	t.Cleanup(func() { columnMigrations = orig })
+
+	db, err := Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	_ = db.Close()
+}
+
+func TestCorrections_LookupPrefersMostRecent(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+	failed := []string{"git", "comit"}
+	for _, c := range []Correction{
+		{ContextKey: "ctx", Tool: "git", Subcommand: "commit", FailedArgv: failed, FixedArgv: []string{"git", "commit", "-a"}, Last: now},
+		{ContextKey: "ctx", Tool: "git", Subcommand: "commit", FailedArgv: failed, FixedArgv: []string{"git", "commit"}, Last: now.Add(-time.Minute)},
+		{ContextKey: "ctx", Tool: "git", Subcommand: "commit", FailedArgv: failed, FixedArgv: []string{"git", "commit"}, Last: now.Add(-time.Second)},
+	} {
+		if err := db.RecordCorrection(c); err != nil {
+			t.Fatalf("RecordCorrection: %v", err)
+		}
+	}
+
+	c, ok, err := db.LookupCorrection("ctx", "git", failed)
+	if err != nil || !ok {
+		t.Fatalf("LookupCorrection: ok=%v err=%v", ok, err)
+	}
+	if strings.Join(c.FixedArgv, " ") != "git commit -a" || c.Count != 1 {
+		t.Fatalf("LookupCorrection = %+v", c)
+	}
+
+	if _, ok, err := db.LookupCorrection("other", "git", failed); err != nil || ok {
+		t.Fatalf("LookupCorrection(other ctx): ok=%v err=%v", ok, err)
+	}
+}
+
+func TestAcceptStreak(t *testing.T) {
+	db := openTestDB(t)
+	failed := []string{"git", "comit"}
+	fixed := []string{"git", "commit"}
+	base := time.Now()
+	for i, choice := range []string{ChoiceYes, ChoiceNo, ChoiceYes, ChoiceAuto, ChoiceYes} {
+		if err := db.RecordConfirmChoice(ConfirmChoice{
+			At: base.Add(time.Duration(i) * time.Second), ContextKey: "ctx", Tool: "git",
+			FailedArgv: failed, FixedArgv: fixed, Choice: choice,
+		}); err != nil {
+			t.Fatalf("RecordConfirmChoice: %v", err)
+		}
+	}
+
+	n, err := db.AcceptStreak("ctx", "git", failed, fixed)
+	if err != nil {
+		t.Fatalf("AcceptStreak: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("AcceptStreak = %d, want 3", n)
+	}
+	if n, err := db.AcceptStreak("ctx", "git", failed, []string{"git", "status"}); err != nil || n != 0 {
+		t.Fatalf("AcceptStreak(other fix) = %d, %v", n, err)
+	}
+}
+
+func TestHelpVocab(t *testing.T) {
+	db := openTestDB(t)
+	if _, ok, err := db.GetHelpVocab("sha", ""); err != nil || ok {
+		t.Fatalf("GetHelpVocab(empty db) ok=%v err=%v", ok, err)
+	}
+
+	want := HelpVocab{ToolSHA: "sha", Subcommand: "log", Subcommands: []string{"a"}, Flags: []string{"--pretty", "-n"}}
+	if err := db.PutHelpVocab(want); err != nil {
+		t.Fatalf("PutHelpVocab: %v", err)
+	}
+	// Empty results are cached as well.
+	if err := db.PutHelpVocab(HelpVocab{ToolSHA: "sha"}); err != nil {
+		t.Fatalf("PutHelpVocab(empty): %v", err)
+	}
+
+	got, ok, err := db.GetHelpVocab("sha", "log")
+	if err != nil || !ok {
+		t.Fatalf("GetHelpVocab ok=%v err=%v", ok, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetHelpVocab = %+v, want %+v", got, want)
+	}
+	if got, ok, err := db.GetHelpVocab("sha", ""); err != nil || !ok || len(got.Flags) != 0 {
+		t.Fatalf("GetHelpVocab(root) = %+v ok=%v err=%v", got, ok, err)
+	}
+}
+
+func TestCompletionCache(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.UpsertTool(ToolIdentity{ExePath: "/bin/kubectl", SHA256: "ksha", VersionStr: "v1"}); err != nil {
+		t.Fatalf("UpsertTool: %v", err)
+	}
+	if kind, err := db.ToolCompletion("ksha"); err != nil || kind != "" {
+		t.Fatalf("ToolCompletion(unchecked) = %q, %v", kind, err)
+	}
+	if kind, err := db.ToolCompletion("missing"); err != nil || kind != "" {
+		t.Fatalf("ToolCompletion(missing) = %q, %v", kind, err)
+	}
+
+	args := []string{"config", ""}
+	if _, ok, err := db.GetCompletion("ksha", args); err != nil || ok {
+		t.Fatalf("GetCompletion(empty) ok=%v err=%v", ok, err)
+	}
+	if err := db.PutCompletion("ksha", args, []string{"view", "use-context"}); err != nil {
+		t.Fatalf("PutCompletion: %v", err)
+	}
+	words, ok, err := db.GetCompletion("ksha", args)
+	if err != nil || !ok || strings.Join(words, " ") != "view use-context" {
+		t.Fatalf("GetCompletion = %v ok=%v err=%v", words, ok, err)
+	}
+}
+
+func TestPerfQueries(t *testing.T) {
+	db := openTestDB(t)
+	oldID, err := db.UpsertTool(ToolIdentity{ExePath: "/bin/git", SHA256: "old", VersionStr: "git version 2.39.0"})
+	if err != nil {
+		t.Fatalf("UpsertTool: %v", err)
+	}
+	newID, err := db.UpsertTool(ToolIdentity{ExePath: "/bin/git", SHA256: "new", VersionStr: "git version 2.43.0"})
+	if err != nil {
+		t.Fatalf("UpsertTool: %v", err)
+	}
+	now := time.Now()
+	for i, inv := range []Invocation{
+		{ToolID: oldID, ArgvJSON: `["git","status"]`, DurationMS: 100, CPUUserMS: 40, MaxRSSKB: 2048},
+		{ToolID: oldID, ArgvJSON: `["git","status"]`, DurationMS: 120},
+		{ToolID: newID, ArgvJSON: `["git","status"]`, DurationMS: 400, MaxRSSKB: 4096},
+		{ToolID: newID, ArgvJSON: `["git","status"]`, DurationMS: 9000, ExitCode: 1, Class: "error"},
+		{ToolID: newID, ArgvJSON: `["git","log"]`, DurationMS: 50},
+		{ToolID: newID, ArgvJSON: `["git","push"]`, DurationMS: 60000, ExitCode: 130, Class: "interrupted"},
+	} {
+		inv.At = now.Add(time.Duration(i) * time.Second)
+		inv.ContextKey, inv.Tool, inv.Mode = "ctx", "git", "pipes"
+		if err := db.InsertInvocation(inv); err != nil {
+			t.Fatalf("InsertInvocation: %v", err)
+		}
+	}
+
+	slow, err := db.SlowestCommands("ctx", "git", 10)
+	if err != nil {
+		t.Fatalf("SlowestCommands: %v", err)
+	}
+	if len(slow) != 2 || slow[0].ArgvJSON != `["git","status"]` || slow[0].Runs != 4 || slow[0].MaxMS != 9000 || slow[0].MaxRSSKB != 4096 {
+		t.Fatalf("SlowestCommands = %+v", slow)
+	}
+
+	timings, err := db.TimingsByToolVersion("ctx", "")
+	if err != nil {
+		t.Fatalf("TimingsByToolVersion: %v", err)
+	}
+	var status []VersionTiming
+	for _, v := range timings {
+		if v.ArgvJSON == `["git","status"]` {
+			status = append(status, v)
+		}
+	}
+	if len(status) != 2 || status[0].Version != "git version 2.39.0" || status[0].AvgMS != 110 ||
+		status[1].Version != "git version 2.43.0" || status[1].AvgMS != 400 || status[1].Runs != 1 {
+		t.Fatalf("TimingsByToolVersion = %+v", timings)
+	}
+}
+
+func TestInvocationTrees(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+	for i, inv := range []Invocation{
+		{ContextKey: "ctx", Tool: "make", ArgvJSON: `["make"]`, RunID: "old"},
+		{ContextKey: "other", Tool: "go", ArgvJSON: `["go","build"]`, ParentRunID: "m1", RunID: "g1"},
+		{ContextKey: "ctx", Tool: "git", ArgvJSON: `["git","status"]`, ParentRunID: "g1"},
+		{ContextKey: "ctx", Tool: "make", ArgvJSON: `["make","all"]`, RunID: "m1"},
+		{ContextKey: "ctx", Tool: "git", ArgvJSON: `["git","log"]`, ParentRunID: "gone"},
+	} {
+		inv.At = now.Add(time.Duration(i) * time.Second)
+		inv.Mode = "pipes"
+		if err := db.InsertInvocation(inv); err != nil {
+			t.Fatalf("InsertInvocation: %v", err)
+		}
+	}
+
+	invs, err := db.ListInvocationTrees("ctx", "", 2)
+	if err != nil {
+		t.Fatalf("ListInvocationTrees: %v", err)
+	}
+	var got []string
+	for _, inv := range invs {
+		got = append(got, inv.ArgvJSON)
+	}
+	// The two newest roots (an orphan counts as one), with make's calls in any context.
+	want := []string{`["go","build"]`, `["git","status"]`, `["make","all"]`, `["git","log"]`}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("ListInvocationTrees = %v, want %v", got, want)
+	}
+
+	cands, err := db.ListSuccessCandidates("git", "ctx", 10)
+	if err != nil {
+		t.Fatalf("ListSuccessCandidates: %v", err)
+	}
+	if len(cands) != 0 {
+		t.Fatalf("nested calls should not be suggestion candidates: %+v", cands)
+	}
+}
+
+func TestToolIdentifyQueue(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+	v1, v2 := FileStamp{Size: 10, MtimeNS: 1}, FileStamp{Size: 12, MtimeNS: 2}
+
+	insert := func(exe string, toolID int64) int64 {
+		t.Helper()
+		id, err := db.InsertInvocationID(Invocation{At: now, ContextKey: "ctx", Tool: "new", ExePath: exe, ToolID: toolID, ArgvJSON: `["new"]`, Mode: "pipes"})
+		if err != nil {
+			t.Fatalf("InsertInvocationID: %v", err)
+		}
+		return id
+	}
+	queue := func(exe string, stamp FileStamp, id int64, at time.Time, want bool) {
+		t.Helper()
+		if start, err := db.QueueToolIdentify(exe, stamp, id, at); err != nil || start != want {
+			t.Fatalf("QueueToolIdentify(%s, %+v) = %v, %v; want %v", exe, stamp, start, err, want)
+		}
+	}
+
+	old1, old2 := insert("/bin/new", 0), insert("/bin/new", 0)
+	queue("/bin/new", v1, old1, now, true)
+	queue("/bin/new", v1, old2, now.Add(time.Minute), false) // claimed
+	insert("/bin/new", 7)
+	// Upgraded within the claim: the new binary gets its own identifier.
+	upgraded := insert("/bin/new", 0)
+	queue("/bin/new", v2, upgraded, now.Add(2*time.Minute), true)
+	queue("/bin/gone", v1, insert("/bin/gone", 0), now, true)
+	insert("/bin/other", 0)
+
+	queued, err := db.QueuedTools()
+	if err != nil || strings.Join(queued, " ") != "/bin/gone /bin/new" {
+		t.Fatalf("QueuedTools = %v, %v", queued, err)
+	}
+
+	// The identifier started for v1 finishes after the upgrade was queued:
+	// it credits only v1's invocations and leaves v2 to its own identifier.
+	if err := db.FinishToolIdentify("/bin/new", v1, now.Add(time.Minute), 9); err != nil {
+		t.Fatalf("FinishToolIdentify: %v", err)
+	}
+	if queued, err := db.QueuedTools(); err != nil || strings.Join(queued, " ") != "/bin/gone /bin/new" {
+		t.Fatalf("QueuedTools with v2 still queued = %v, %v", queued, err)
+	}
+	if err := db.FinishToolIdentify("/bin/new", v2, now.Add(3*time.Minute), 10); err != nil {
+		t.Fatalf("FinishToolIdentify: %v", err)
+	}
+	if err := db.FinishToolIdentify("/bin/gone", v1, now.Add(time.Minute), 0); err != nil {
+		t.Fatalf("FinishToolIdentify: %v", err)
+	}
+	if queued, err := db.QueuedTools(); err != nil || len(queued) != 0 {
+		t.Fatalf("QueuedTools after finishing = %v, %v", queued, err)
+	}
+
+	invs, err := db.ListInvocations("ctx", "new", 10)
+	if err != nil {
+		t.Fatalf("ListInvocations: %v", err)
+	}
+	var got []string
+	for _, inv := range invs {
+		got = append(got, inv.ExePath+":"+strconv.FormatInt(inv.ToolID, 10))
+	}
+	sort.Strings(got)
+	want := []string{"/bin/gone:0", "/bin/new:10", "/bin/new:7", "/bin/new:9", "/bin/new:9", "/bin/other:0"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("tool ids = %v, want %v", got, want)
+	}
+
+	// An identifier that finds another binary than the one queued before it
+	// started credits nobody, and clears the queue.
+	replaced := insert("/bin/new", 0)
+	queue("/bin/new", v1, replaced, now.Add(time.Hour), true)
+	if err := db.FinishToolIdentify("/bin/new", v2, now.Add(2*time.Hour), 10); err != nil {
+		t.Fatalf("FinishToolIdentify: %v", err)
+	}
+	if queued, err := db.QueuedTools(); err != nil || len(queued) != 0 {
+		t.Fatalf("QueuedTools after a replaced binary = %v, %v", queued, err)
+	}
+	var pending int
+	if err := db.QueryRowContext(context.Background(), `SELECT COUNT(*) FROM tool_identify_pending`).Scan(&pending); err != nil || pending != 0 {
+		t.Fatalf("pending rows left = %d, %v", pending, err)
+	}
+	if inv, err := db.GetInvocation(replaced); err != nil || inv.ToolID != 0 {
+		t.Fatalf("replaced binary's invocation tool_id = %d, %v; want none", inv.ToolID, err)
+	}
+}
+
+func TestVocabProbeQueue(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+	queue := func(exe string, argv []string, at time.Time, want bool) {
+		t.Helper()
+		if start, err := db.QueueVocabProbe(exe, argv, at); err != nil || start != want {
+			t.Fatalf("QueueVocabProbe(%s, %v) = %v, %v; want %v", exe, argv, start, err, want)
+		}
+	}
+	queue("/bin/tool", []string{"tool", "stauts"}, now, true)
+	queue("/bin/tool", []string{"tool", "stauts"}, now.Add(time.Minute), false) // claimed
+	queue("/bin/tool", []string{"tool", "lgo"}, now.Add(time.Minute), true)
+	queue("/bin/tool", []string{"tool", "stauts"}, now.Add(time.Hour), true) // claim expired
+
+	probes, err := db.QueuedVocabProbes()
+	if err != nil || len(probes) != 2 || strings.Join(probes[0].Argv, " ") != "tool lgo" || probes[1].ExePath != "/bin/tool" {
+		t.Fatalf("QueuedVocabProbes = %+v, %v", probes, err)
+	}
+	for _, p := range probes {
+		if err := db.FinishVocabProbe(p); err != nil {
+			t.Fatalf("FinishVocabProbe: %v", err)
+		}
+	}
+	if probes, err := db.QueuedVocabProbes(); err != nil || len(probes) != 0 {
+		t.Fatalf("QueuedVocabProbes after finishing = %+v, %v", probes, err)
+	}
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+
+	"github.com/joelklabo/ackchyually/internal/toolversion"
+)
+
+// parseToolVersion parses a tool_identities.version_str, which starts with
+// the tool's basename (that may have digits of its own, as in python3.12).
+func parseToolVersion(exePath, versionStr string) string {
+	return toolversion.Parse(strings.TrimPrefix(versionStr, filepath.Base(exePath)+" "))
+}
+
+// fillToolVersions parses the version of tools identified before the version
+// column existed.
+func fillToolVersions(db *sql.DB) error {
+	rows, err := db.QueryContext(context.Background(), `SELECT id, exe_path, version_str FROM tool_identities`)
+	if err != nil {
+		return err
+	}
+	type fill struct {
+		id      int64
+		version string
+	}
+	var fills []fill
+	for rows.Next() {
+		var id int64
+		var exe, raw string
+		if err := rows.Scan(&id, &exe, &raw); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if v := parseToolVersion(exe, raw); v != "" {
+			fills = append(fills, fill{id, v})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	for _, f := range fills {
+		if _, err := db.ExecContext(context.Background(), `UPDATE tool_identities SET version = ? WHERE id = ?`, f.version, f.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// VocabProbe is a failed command whose tool hasn't had its --help or
+// completions cached for it yet.
+type VocabProbe struct {
+	ExePath string
+	Argv    []string
+}
+
+// QueueVocabProbe queues argv, run as exe, for the background identifier to
+// ask the tool about. It reports whether the caller should start one: false
+// if one was started recently for this same command.
+func (db *DB) QueueVocabProbe(exe string, argv []string, now time.Time) (bool, error) {
+	res, err := db.ExecContext(context.Background(), `
+INSERT INTO vocab_probe_queue(exe_path, argv_json, queued_at) VALUES (?, ?, ?)
+ON CONFLICT(exe_path, argv_json) DO UPDATE SET queued_at = excluded.queued_at
+WHERE queued_at < ?`,
+		exe, MustJSON(argv), now.Unix(), now.Add(-identifyClaimTTL).Unix())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// QueuedVocabProbes returns the queued probes, oldest first.
+func (db *DB) QueuedVocabProbes() ([]VocabProbe, error) {
+	rows, err := db.QueryContext(context.Background(), `
+SELECT exe_path, argv_json FROM vocab_probe_queue ORDER BY queued_at ASC, exe_path ASC, argv_json ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []VocabProbe
+	for rows.Next() {
+		var p VocabProbe
+		var argvJSON string
+		if err := rows.Scan(&p.ExePath, &argvJSON); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(argvJSON), &p.Argv); err != nil {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// FinishVocabProbe removes a probe from the queue.
+func (db *DB) FinishVocabProbe(p VocabProbe) error {
+	_, err := db.ExecContext(context.Background(), `
+DELETE FROM vocab_probe_queue WHERE exe_path = ? AND argv_json = ?`, p.ExePath, MustJSON(p.Argv))
+	return err
+}
@@ -3,6 +3,7 @@ package toolid
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -28,36 +29,11 @@ func Identify(exe string) (ToolIdentity, error) {
 	mu.Lock()
 	defer mu.Unlock()
 
-	st, err := os.Stat(exe)
-	if err != nil {
-		return ToolIdentity{}, err
-	}
-	size := st.Size()
-	mtimeNS := st.ModTime().UnixNano()
-
 	var ti ToolIdentity
-	err = store.WithDB(func(db *store.DB) error {
-		sha := ""
-		cached, err2 := db.GetToolPathCache(exe)
-		if err2 == nil {
-			if cached.FileSize == size && cached.FileMtimeNS == mtimeNS {
-				sha = cached.SHA256
-			}
-		}
-		if sha == "" {
-			sha2, err3 := sha256File(exe)
-			if err3 != nil {
-				return err3
-			}
-			sha = sha2
-			if err := db.UpsertToolPathCache(store.ToolPathCache{
-				ExePath:     exe,
-				FileSize:    size,
-				FileMtimeNS: mtimeNS,
-				SHA256:      sha,
-			}); err != nil {
-				_ = err // best-effort
-			}
+	err := store.WithDB(func(db *store.DB) error {
+		sha, err2 := cachedSHA256(db, exe)
+		if err2 != nil {
+			return err2
 		}
 
 		found, err2 := db.GetToolBySHA(sha)
@@ -86,6 +62,127 @@ func Identify(exe string) (ToolIdentity, error) {
 	return ti, err
 }
 
+// Hash returns the SHA-256 of exe, cached by path, size and mtime. Unlike
+// Identify it never runs exe.
+func Hash(exe string) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var sha string
+	err := store.WithDB(func(db *store.DB) error {
+		var err error
+		sha, err = cachedSHA256(db, exe)
+		return err
+	})
+	return sha, err
+}
+
+func cachedSHA256(db *store.DB, exe string) (string, error) {
+	st, err := os.Stat(exe)
+	if err != nil {
+		return "", err
+	}
+	size := st.Size()
+	mtimeNS := st.ModTime().UnixNano()
+
+	if cached, err := db.GetToolPathCache(exe); err == nil {
+		if cached.FileSize == size && cached.FileMtimeNS == mtimeNS {
+			return cached.SHA256, nil
+		}
+	}
+	sha, err := sha256File(exe)
+	if err != nil {
+		return "", err
+	}
+	if err := db.UpsertToolPathCache(store.ToolPathCache{
+		ExePath:     exe,
+		FileSize:    size,
+		FileMtimeNS: mtimeNS,
+		SHA256:      sha,
+	}); err != nil {
+		_ = err // best-effort
+	}
+	return sha, nil
+}
+
+// Lookup returns the identity already recorded for exe, without hashing it
+// or running it: it only succeeds if exe hasn't changed since Identify last
+// saw it. Shims use it so that a new binary doesn't delay the command; they
+// queue it for IdentifyQueued instead.
+func Lookup(exe string) (ToolIdentity, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	st, err := os.Stat(exe)
+	if err != nil {
+		return ToolIdentity{}, false
+	}
+	var ti ToolIdentity
+	err = store.WithDB(func(db *store.DB) error {
+		cached, err := db.GetToolPathCache(exe)
+		if err != nil {
+			return err
+		}
+		if cached.FileSize != st.Size() || cached.FileMtimeNS != st.ModTime().UnixNano() {
+			return errStale
+		}
+		found, err := db.GetToolBySHA(cached.SHA256)
+		if err != nil {
+			return err
+		}
+		ti = ToolIdentity{
+			ID:         found.ID,
+			ExePath:    found.ExePath,
+			SHA256:     found.SHA256,
+			VersionStr: found.VersionStr,
+		}
+		return nil
+	})
+	return ti, err == nil && ti.ID != 0
+}
+
+var errStale = errors.New("tool changed since it was identified")
+
+// Stamp returns exe's size and mtime, which tell one binary at a path from
+// the next without hashing it.
+func Stamp(exe string) (store.FileStamp, error) {
+	st, err := os.Stat(exe)
+	if err != nil {
+		return store.FileStamp{}, err
+	}
+	return store.FileStamp{Size: st.Size(), MtimeNS: st.ModTime().UnixNano()}, nil
+}
+
+// IdentifyQueued identifies every queued executable and fills in the tool of
+// the invocations recorded while it was unknown. Executables that can't be
+// identified (e.g. removed since) are dropped from the queue.
+func IdentifyQueued() error {
+	var queued []string
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		queued, err = db.QueuedTools()
+		return err
+	}); err != nil {
+		return err
+	}
+	for _, exe := range queued {
+		// The binary identified must be the one stamped before and after:
+		// one replaced meanwhile can't be told apart from the one that ran.
+		since := time.Now()
+		stamp, err := Stamp(exe)
+		ti, idErr := Identify(exe)
+		if after, err2 := Stamp(exe); err != nil || idErr != nil || err2 != nil || after != stamp {
+			ti = ToolIdentity{}
+		}
+		if err := store.WithDB(func(db *store.DB) error {
+			return db.FinishToolIdentify(exe, stamp, since, ti.ID)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func sha256File(path string) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/joelklabo/ackchyually/internal/store"
 )
@@ -101,3 +102,60 @@ exit 0
 		t.Fatalf("expected sha to change; got %q", second.SHA256)
 	}
 }
+
+func TestLookupAndIdentifyQueued(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", filepath.Join(tmp, "home"))
+
+	exe := filepath.Join(tmp, "tool.sh")
+	//nolint:gosec
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\necho v1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := Lookup(exe); ok {
+		t.Fatalf("Lookup should not know a binary never identified")
+	}
+	stamp, err := Stamp(exe)
+	if err != nil {
+		t.Fatalf("Stamp: %v", err)
+	}
+	var invID int64
+	if err := store.WithDB(func(db *store.DB) error {
+		var err error
+		invID, err = db.InsertInvocationID(store.Invocation{At: time.Now(), ContextKey: "ctx", Tool: "tool.sh", ExePath: exe, ArgvJSON: `["tool.sh"]`, Mode: "pipes"})
+		if err != nil {
+			return err
+		}
+		_, err = db.QueueToolIdentify(exe, stamp, invID, time.Now())
+		return err
+	}); err != nil {
+		t.Fatalf("QueueToolIdentify: %v", err)
+	}
+	if err := IdentifyQueued(); err != nil {
+		t.Fatalf("IdentifyQueued: %v", err)
+	}
+
+	ti, ok := Lookup(exe)
+	if !ok || ti.ID == 0 || ti.VersionStr != "tool.sh v1" {
+		t.Fatalf("Lookup after IdentifyQueued = %#v, %v", ti, ok)
+	}
+	if err := store.WithDB(func(db *store.DB) error {
+		inv, err := db.GetInvocation(invID)
+		if err == nil && inv.ToolID != ti.ID {
+			t.Errorf("queued invocation tool_id = %d, want %d", inv.ToolID, ti.ID)
+		}
+		return err
+	}); err != nil {
+		t.Fatalf("GetInvocation: %v", err)
+	}
+
+	// A rewritten binary is unknown again until it is identified.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(exe, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Lookup(exe); ok {
+		t.Fatalf("Lookup should not trust the cache after the binary changed")
+	}
+}
@@ -0,0 +1,81 @@
+// Package toolversion extracts comparable versions from what tools print for
+// --version.
+package toolversion
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// versionRe matches major.minor[.patch] with an optional pre-release tag. A
+// bare number is never a version: it is as likely a vendor build ("Apple
+// Git-146"), a year or an architecture.
+var versionRe = regexp.MustCompile(`(?i)(?:^|[^0-9.])v?(\d+)\.(\d+)(?:\.(\d+))?(?:[-.]?((?:alpha|beta|rc|pre|dev)[0-9a-z.]*))?`)
+
+// Parse returns the version in a tool's --version output as canonical semver
+// ("v2.44.0"), or "" if there is none. Lines that say "version" are searched
+// first, so banners, copyright lines and URLs matter less than where they are;
+// otherwise the first line with a version wins.
+func Parse(output string) string {
+	lines := strings.Split(output, "\n")
+	for _, l := range lines {
+		if strings.Contains(strings.ToLower(l), "version") {
+			if v := parseLine(l); v != "" {
+				return v
+			}
+		}
+	}
+	for _, l := range lines {
+		if v := parseLine(l); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func parseLine(l string) string {
+	for _, m := range versionRe.FindAllStringSubmatch(l, -1) {
+		parts := []string{m[1], m[2], m[3]}
+		if parts[2] == "" {
+			parts[2] = "0"
+		}
+		for i, p := range parts {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				parts = nil
+				break
+			}
+			parts[i] = strconv.Itoa(n) // semver rejects leading zeros
+		}
+		if parts == nil {
+			continue
+		}
+		v := "v" + strings.Join(parts, ".")
+		if m[4] != "" {
+			v += "-" + strings.ToLower(strings.Trim(m[4], "."))
+		}
+		if semver.IsValid(v) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Normalize turns a version someone typed ("2.40", "v1.2.3") into canonical
+// semver, reporting false if it isn't one.
+func Normalize(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "v") {
+		s = "v" + s
+	}
+	if !semver.IsValid(s) {
+		return "", false
+	}
+	return semver.Canonical(s), true
+}
+
+// Compare orders two canonical versions like semver.Compare; "" sorts first.
+func Compare(a, b string) int { return semver.Compare(a, b) }
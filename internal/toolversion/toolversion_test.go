@@ -0,0 +1,41 @@
+package toolversion
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"git version 2.44.0", "v2.44.0"},
+		{"git version 2.39.3 (Apple Git-146)", "v2.39.3"},
+		{"Apple Git-146", ""},
+		{"gh version 2.44.1 (2024-02-16)\nhttps://github.com/cli/cli/releases/tag/v2.44.1\n", "v2.44.1"},
+		{"go version go1.22.0 linux/amd64", "v1.22.0"},
+		{"jq-1.7.1", "v1.7.1"},
+		{"Docker version 24.0.7, build afdd53b", "v24.0.7"},
+		{"Client Version: v1.29.01\nKustomize Version: v5.0.4", "v1.29.1"},
+		{"Terraform v1.7.0-rc.1\non darwin_arm64", "v1.7.0-rc.1"},
+		{"OpenSSH_9.6p1, LibreSSL 3.3.6", "v9.6.0"},
+		{"curl 8.4.0 (x86_64-apple-darwin23.0) libcurl/8.4.0", "v8.4.0"},
+		{"Welcome to Foo 2024.1 (x86_64)\nCopyright 2024\nfoo version 3.2", "v3.2.0"},
+		{"tool 2.0beta2", "v2.0.0-beta2"},
+		{"mytool (version unknown)", ""},
+		{"usage: tool [-h]", ""},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.in); got != tt.want {
+			t.Errorf("Parse(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeAndCompare(t *testing.T) {
+	v, ok := Normalize("2.40")
+	if !ok || v != "v2.40.0" {
+		t.Fatalf("Normalize(2.40) = %q, %v", v, ok)
+	}
+	if _, ok := Normalize("latest"); ok {
+		t.Fatalf("Normalize(latest) should fail")
+	}
+	if Compare("v2.44.1", v) <= 0 || Compare("v2.9.0", v) >= 0 || Compare("", v) >= 0 {
+		t.Fatalf("Compare ordering is wrong")
+	}
+}